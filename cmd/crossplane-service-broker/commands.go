@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"code.cloudfoundry.org/lager"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/vshn/crossplane-service-broker/pkg/brokerapi"
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+	"github.com/vshn/crossplane-service-broker/pkg/crossplane"
+	"github.com/vshn/crossplane-service-broker/pkg/logging"
+	"github.com/vshn/crossplane-service-broker/pkg/platform"
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+)
+
+// validateConfig reads the configuration and parses the plan update rules, just like serve does on startup.
+func validateConfig(args []string) error {
+	fs := newFlagSet("config validate", "Validate the configuration and exit.")
+	configFile := configFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.ReadConfigFile(*configFile, os.LookupEnv)
+	if err != nil {
+		return err
+	}
+	if _, err := crossplane.NewPlanUpdateChecker(cfg); err != nil {
+		return err
+	}
+	fmt.Println("configuration is valid")
+	return nil
+}
+
+// catalog prints the catalog of the configured services as JSON.
+func catalog(args []string) error {
+	fs := newFlagSet("catalog", "Print the catalog the broker would serve against the current kubeconfig.")
+	configFile := configFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.ReadConfigFile(*configFile, os.LookupEnv)
+	if err != nil {
+		return err
+	}
+	// Logs are written to stderr to keep the catalog on stdout parseable.
+	logger := logging.NewLogger(os.Stderr, cfg, "crossplane-service-broker").WithData(lager.Data{"version": version})
+
+	cp, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+	pc, err := crossplane.NewPlanUpdateChecker(cfg)
+	if err != nil {
+		return err
+	}
+
+	services, err := brokerapi.New(cp, logger.WithData(lager.Data{"component": "brokerapi"}), pc, nil).Services(context.Background())
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]interface{}{"services": services})
+}
+
+// lint prints all problems of the XRDs and Compositions of the configured services and fails if there are any.
+func lint(args []string) error {
+	fs := newFlagSet("lint", "Check the XRDs and Compositions of the configured services for problems.")
+	configFile := configFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.ReadConfigFile(*configFile, os.LookupEnv)
+	if err != nil {
+		return err
+	}
+	logger := logging.NewLogger(os.Stderr, cfg, "crossplane-service-broker").WithData(lager.Data{"version": version})
+
+	cp, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+
+	problems, err := cp.Lint(reqcontext.NewReqContext(context.Background(), logger, nil))
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		fmt.Println(p.Error())
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problem(s) in the XRDs and Compositions of the services %v", len(problems), cfg.ServiceIDs)
+	}
+	fmt.Println("no problems found")
+	return nil
+}
+
+// explainPlanUpdate explains whether the plan update rules allow updating an instance from one plan to another.
+func explainPlanUpdate(args []string) error {
+	fs := newFlagSet("plan-update explain", "Explain whether the plan update rules allow changing from the plan OLD_PLAN_ID to NEW_PLAN_ID.")
+	configFile := configFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("expected the arguments OLD_PLAN_ID and NEW_PLAN_ID")
+	}
+
+	cfg, err := config.ReadConfigFile(*configFile, os.LookupEnv)
+	if err != nil {
+		return err
+	}
+	logger := logging.NewLogger(os.Stderr, cfg, "crossplane-service-broker").WithData(lager.Data{"version": version})
+	pc, err := crossplane.NewPlanUpdateChecker(cfg)
+	if err != nil {
+		return err
+	}
+	cp, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+
+	rctx := reqcontext.NewReqContext(context.Background(), logger, nil)
+	oldPlan, err := cp.Plan(rctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	newPlan, err := cp.Plan(rctx, fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	xrd, err := cp.ServiceXRD(rctx, oldPlan.Labels.ServiceID)
+	if err != nil {
+		return err
+	}
+	if xrd != nil {
+		oldPlan.UpdateTargets = xrd.UpdateTargets(oldPlan)
+	}
+
+	d := pc.Explain(*oldPlan, *newPlan)
+	switch {
+	case d.Allowed && d.Rule == "":
+		fmt.Println("allowed, neither the size nor the SLA changes")
+	case d.Allowed:
+		fmt.Printf("allowed by rule %q\n", d.Rule)
+	default:
+		fmt.Println("denied:")
+		for _, r := range d.Reasons {
+			fmt.Printf("  %s\n", r)
+		}
+	}
+	return nil
+}
+
+// listInstances prints the instances of the configured services, optionally filtered by service and platform context.
+func listInstances(args []string) error {
+	fs := newFlagSet("instances", "List the instances of the configured services.")
+	configFile := configFlag(fs)
+	serviceID := fs.String("service", "", "only list instances of this service ID")
+	filter := platform.Context{}
+	fs.StringVar(&filter.Platform, "platform", "", "only list instances provisioned from this platform, e.g. cloudfoundry or kubernetes")
+	fs.StringVar(&filter.OrganizationGUID, "org", "", "only list instances of this Cloud Foundry organization GUID")
+	fs.StringVar(&filter.SpaceGUID, "space", "", "only list instances of this Cloud Foundry space GUID")
+	fs.StringVar(&filter.Namespace, "namespace", "", "only list instances of this Kubernetes namespace")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.ReadConfigFile(*configFile, os.LookupEnv)
+	if err != nil {
+		return err
+	}
+	logger := logging.NewLogger(os.Stderr, cfg, "crossplane-service-broker").WithData(lager.Data{"version": version})
+
+	cp, err := connect(cfg)
+	if err != nil {
+		return err
+	}
+
+	selector := crossplane.PlatformSelector(filter)
+	if *serviceID != "" {
+		selector[crossplane.ServiceIDLabel] = *serviceID
+	}
+	instances, err := cp.Instances(reqcontext.NewReqContext(context.Background(), logger, nil), selector)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INSTANCE\tSERVICE\tPLAN\tPLATFORM\tORGANIZATION/NAMESPACE\tSPACE")
+	for _, i := range instances {
+		pc, err := i.PlatformContext()
+		if err != nil {
+			logger.Error("invalid-platform-context", err, lager.Data{"instance-id": i.ID()})
+		}
+		owner := pc.OrganizationName
+		if owner == "" {
+			owner = pc.OrganizationGUID
+		}
+		if owner == "" {
+			owner = pc.Namespace
+		}
+		space := pc.SpaceName
+		if space == "" {
+			space = pc.SpaceGUID
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", i.ID(), i.Labels.ServiceName, i.Labels.PlanName, pc.Platform, owner, space)
+	}
+	return w.Flush()
+}
+
+// connect returns a Crossplane client for the cluster of the configured kubeconfig.
+func connect(cfg *config.Config) (*crossplane.Crossplane, error) {
+	rConfig, err := clientcmd.BuildConfigFromFlags("", cfg.Kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load k8s REST config: %w", err)
+	}
+	return crossplane.New(cfg, rConfig)
+}
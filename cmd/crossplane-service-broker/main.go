@@ -1,24 +1,14 @@
 package main
 
 import (
-	"context"
 	"errors"
+	"flag"
 	"fmt"
-	"net/http"
+	"io"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
+	"strings"
 
-	"code.cloudfoundry.org/lager"
-	"github.com/gorilla/mux"
-	"github.com/vshn/crossplane-service-broker/pkg/api/auth"
-	"k8s.io/client-go/tools/clientcmd"
-
-	"github.com/vshn/crossplane-service-broker/pkg/api"
-	"github.com/vshn/crossplane-service-broker/pkg/brokerapi"
 	"github.com/vshn/crossplane-service-broker/pkg/config"
-	"github.com/vshn/crossplane-service-broker/pkg/crossplane"
 )
 
 const (
@@ -29,80 +19,87 @@ var (
 	version = "dev"
 )
 
-func main() {
-	ctx := context.Background()
-	ctx, cancel := context.WithCancel(ctx)
-
-	logger := lager.NewLogger("crossplane-service-broker").WithData(lager.Data{"version": version})
-	logger.RegisterSink(lager.NewPrettySink(os.Stdout, lager.DEBUG))
-
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
-
-	defer func() {
-		signal.Stop(signalChan)
-		cancel()
-	}()
+// errLogged is returned by commands which already logged the reason of their failure.
+var errLogged = errors.New("failure has been logged")
+
+// commands are the subcommands of the binary, in the order they are listed in the usage.
+var commands = []struct {
+	name        string
+	description string
+	run         func(args []string) error
+}{
+	{"serve", "Serve the Open Service Broker API (default command).", serve},
+	{"catalog", "Print the catalog the broker would serve against the current kubeconfig.", catalog},
+	{"config validate", "Validate the configuration and exit.", validateConfig},
+	{"plan-update explain", "Explain whether the plan update rules allow changing between two plans.", explainPlanUpdate},
+	{"instances", "List the instances of the configured services.", listInstances},
+	{"lint", "Check the XRDs and Compositions of the configured services for problems.", lint},
+	{"version", "Print the version and exit.", printVersion},
+}
 
-	if err := run(signalChan, logger); err != nil {
-		logger.Error("application  run failed", err)
+func main() {
+	err := execute(os.Args[1:])
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errLogged):
+		os.Exit(exitCodeErr)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 		os.Exit(exitCodeErr)
 	}
 }
 
-func run(signalChan chan os.Signal, logger lager.Logger) error {
-	logger.Info("starting crossplane-service-broker")
-
-	cfg, err := config.ReadConfig(os.Getenv)
-	if err != nil {
-		return fmt.Errorf("unable to read app env: %w", err)
-	}
-	rConfig, err := clientcmd.BuildConfigFromFlags("", cfg.Kubeconfig)
-	if err != nil {
-		return fmt.Errorf("unable to load k8s REST config: %w", err)
+// execute runs the subcommand selected by args. Without a subcommand, the broker is served.
+func execute(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		switch {
+		case len(args) > 0 && (args[0] == "-version" || args[0] == "--version"):
+			return printVersion(nil)
+		case len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help"):
+			usage(os.Stdout)
+			return nil
+		}
+		return serve(args)
 	}
 
-	router := mux.NewRouter()
-
-	cp, err := crossplane.New(cfg, rConfig)
-	if err != nil {
-		return err
+	for _, c := range commands {
+		words := strings.Fields(c.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == c.name {
+			return c.run(args[len(words):])
+		}
 	}
-	pc, err := crossplane.ParsePlanUpdateRules(cfg.PlanUpdateSizeRule, cfg.PlanUpdateSLARule)
-	if err != nil {
-		return err
+	if args[0] == "help" {
+		usage(os.Stdout)
+		return nil
 	}
-	b := brokerapi.New(cp, logger.WithData(lager.Data{"component": "brokerapi"}), pc)
-
-	serviceBrokerCredential := auth.SingleCredential(cfg.Username, cfg.Password)
-	a := api.New(b, serviceBrokerCredential, cfg.JWKeyRegister, logger.WithData(lager.Data{"component": "api"}))
-	router.NewRoute().Handler(a)
+	usage(os.Stderr)
+	return fmt.Errorf("unknown command %q", strings.Join(args, " "))
+}
 
-	srv := http.Server{
-		Addr:           cfg.ListenAddr,
-		Handler:        router,
-		ReadTimeout:    cfg.ReadTimeout,
-		WriteTimeout:   cfg.WriteTimeout,
-		MaxHeaderBytes: cfg.MaxHeaderBytes,
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: %s [command] [flags]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-20s %s\n", c.name, c.description)
 	}
+	fmt.Fprintf(w, "\nRun '%s <command> -h' for the flags of a command.\n", os.Args[0])
+}
 
-	go func() {
-		logger.Info("server start")
-		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
-			logger.Error("server error", err)
-			signalChan <- syscall.SIGABRT
-		}
-		logger.Info("server shut down")
-	}()
-
-	sig := <-signalChan
-	if sig == syscall.SIGABRT {
-		return errors.New("unable to start server")
+// newFlagSet returns a flag set for the named command, which returns flag.ErrHelp on -h.
+func newFlagSet(name, description string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s [flags]\n\n%s\n\nFlags:\n", os.Args[0], name, description)
+		fs.PrintDefaults()
 	}
+	return fs
+}
 
-	logger.Info("shutting down server", lager.Data{"signal": sig.String()})
+// configFlag registers the -config flag, defaulting to config.EnvConfigFile.
+func configFlag(fs *flag.FlagSet) *string {
+	return fs.String("config", os.Getenv(config.EnvConfigFile), "path to a YAML or JSON config file, settings of env variables take precedence")
+}
 
-	graceCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-	return srv.Shutdown(graceCtx)
+func printVersion(_ []string) error {
+	fmt.Println(version)
+	return nil
 }
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vshn/crossplane-service-broker/pkg/api/auth"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/vshn/crossplane-service-broker/pkg/api"
+	"github.com/vshn/crossplane-service-broker/pkg/audit"
+	"github.com/vshn/crossplane-service-broker/pkg/brokerapi"
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+	"github.com/vshn/crossplane-service-broker/pkg/crossplane"
+	"github.com/vshn/crossplane-service-broker/pkg/logging"
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+	"github.com/vshn/crossplane-service-broker/pkg/tracing"
+)
+
+// configWatchInterval defines how often the config file is checked for changes.
+const configWatchInterval = 10 * time.Second
+
+// passwordSecretCollectInterval defines how often orphaned password Secrets of MariaDB bindings are deleted.
+const passwordSecretCollectInterval = 10 * time.Minute
+
+// serve runs the service broker until it receives SIGINT or SIGTERM.
+func serve(args []string) error {
+	fs := newFlagSet("serve", "Serve the Open Service Broker API (default command).")
+	configFile := configFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.ReadConfigFile(*configFile, os.LookupEnv)
+	if err != nil {
+		return fmt.Errorf("unable to read app env: %w", err)
+	}
+
+	logger := logging.NewLogger(os.Stdout, cfg, "crossplane-service-broker").WithData(lager.Data{"version": version})
+
+	ctx := context.Background()
+	ctx, cancel := context.WithCancel(ctx)
+
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+	defer func() {
+		signal.Stop(signalChan)
+		cancel()
+	}()
+
+	if err := run(ctx, signalChan, *configFile, cfg, logger); err != nil {
+		logger.Error("application  run failed", err)
+		return errLogged
+	}
+	return nil
+}
+
+func run(ctx context.Context, signalChan chan os.Signal, configFile string, cfg *config.Config, logger lager.Logger) error {
+	logger.Info("starting crossplane-service-broker", lager.Data{"log-level": cfg.LogLevel.String(), "log-format": cfg.LogFormat})
+
+	rConfig, err := clientcmd.BuildConfigFromFlags("", cfg.Kubeconfig)
+	if err != nil {
+		return fmt.Errorf("unable to load k8s REST config: %w", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg, version)
+	if err != nil {
+		return fmt.Errorf("unable to set up tracing: %w", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Error("tracing shutdown failed", err)
+		}
+	}()
+
+	router := mux.NewRouter()
+
+	cp, err := crossplane.New(cfg, rConfig)
+	if err != nil {
+		return err
+	}
+	lintCatalog(ctx, cp, logger.WithData(lager.Data{"component": "lint"}))
+	if err := prometheus.Register(crossplane.NewInstanceCollector(cp, logger.WithData(lager.Data{"component": "metrics"}))); err != nil {
+		return fmt.Errorf("unable to register instance metrics: %w", err)
+	}
+	pc, err := crossplane.NewPlanUpdateChecker(cfg)
+	if err != nil {
+		return err
+	}
+	auditLog, err := audit.New(cfg, cp, logger.WithData(lager.Data{"component": "audit"}))
+	if err != nil {
+		return fmt.Errorf("unable to set up audit log: %w", err)
+	}
+	b := brokerapi.New(cp, logger.WithData(lager.Data{"component": "brokerapi"}), pc, auditLog)
+
+	serviceBrokerCredential := auth.SingleCredential(cfg.Username, cfg.Password)
+	apiLogger := logger.WithData(lager.Data{"component": "api"})
+	rl := api.NewRateLimiter(cfg, apiLogger)
+	a := api.New(b, serviceBrokerCredential, cfg.JWKeyRegister, apiLogger, rl.Handler)
+	router.NewRoute().Handler(a)
+
+	srv := http.Server{
+		Addr:           cfg.ListenAddr,
+		Handler:        router,
+		ReadTimeout:    cfg.ReadTimeout,
+		WriteTimeout:   cfg.WriteTimeout,
+		MaxHeaderBytes: cfg.MaxHeaderBytes,
+	}
+
+	serve := srv.ListenAndServe
+	if cfg.TLSEnabled() {
+		cr, err := api.NewCertificateReloader(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile, logger.WithData(lager.Data{"component": "tls"}))
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = cr.TLSConfig()
+		serve = func() error {
+			// certificate and key are provided by the TLSConfig
+			return srv.ListenAndServeTLS("", "")
+		}
+	}
+
+	go func() {
+		logger.Info("server start", lager.Data{"tls": cfg.TLSEnabled(), "client-certificates": cfg.TLSClientCAFile != ""})
+		if err := serve(); !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("server error", err)
+			signalChan <- syscall.SIGABRT
+		}
+		logger.Info("server shut down")
+	}()
+
+	go cp.RunPasswordSecretCollector(ctx, passwordSecretCollectInterval, logger.WithData(lager.Data{"component": "gc"}))
+
+	if configFile != "" {
+		go config.WatchFile(ctx, configFile, configWatchInterval, func() {
+			select {
+			case signalChan <- syscall.SIGHUP:
+			case <-ctx.Done():
+			}
+		})
+	}
+
+	sig := <-signalChan
+	for sig == syscall.SIGHUP {
+		reload(configFile, b, logger)
+		sig = <-signalChan
+	}
+	if sig == syscall.SIGABRT {
+		return errors.New("unable to start server")
+	}
+
+	logger.Info("shutting down server", lager.Data{"signal": sig.String()})
+
+	graceCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err = srv.Shutdown(graceCtx)
+	if cerr := auditLog.Close(graceCtx); cerr != nil {
+		logger.Error("audit log shutdown failed", cerr)
+	}
+	return err
+}
+
+// lintCatalog logs the problems of the XRDs and Compositions of the configured services.
+// The broker starts anyway, as valid services and plans can still be served.
+func lintCatalog(ctx context.Context, cp *crossplane.Crossplane, logger lager.Logger) {
+	problems, err := cp.Lint(reqcontext.NewReqContext(ctx, logger, nil))
+	if err != nil {
+		logger.Error("catalog lint failed", err)
+		return
+	}
+	for _, p := range problems {
+		logger.Error("catalog problem", p, lager.Data{"kind": p.Kind, "name": p.Name})
+	}
+}
+
+// reload reads the configuration again and applies the reloadable settings to the running broker.
+// The current settings are kept if the configuration is invalid.
+func reload(configFile string, b *brokerapi.BrokerAPI, logger lager.Logger) {
+	cfg, err := config.ReadConfigFile(configFile, os.LookupEnv)
+	if err != nil {
+		logger.Error("config reload failed", err)
+		return
+	}
+	pc, err := crossplane.NewPlanUpdateChecker(cfg)
+	if err != nil {
+		logger.Error("config reload failed", err)
+		return
+	}
+	b.Reload(cfg, pc)
+	logger.Info("config reloaded", lager.Data{"service-ids": cfg.ServiceIDs, "enable-metrics": cfg.EnableMetrics})
+}
@@ -15,6 +15,7 @@ import (
 
 	"github.com/vshn/crossplane-service-broker/pkg/api/auth"
 	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+	"github.com/vshn/crossplane-service-broker/pkg/tracing"
 )
 
 // API is a http.Handler
@@ -23,8 +24,9 @@ type API struct {
 	logger lager.Logger
 }
 
-// New creates a new API
-func New(sb domain.ServiceBroker, brokerCredentials []auth.Credential, jwtSigningKeys *jwt.KeyRegister, logger lager.Logger) *API {
+// New creates a new API.
+// The given middlewares are applied to all service broker routes after the request has been authenticated.
+func New(sb domain.ServiceBroker, brokerCredentials []auth.Credential, jwtSigningKeys *jwt.KeyRegister, logger lager.Logger, middlewares ...mux.MiddlewareFunc) *API {
 	rootRouter := mux.NewRouter()
 
 	rootRouter.
@@ -43,9 +45,11 @@ func New(sb domain.ServiceBroker, brokerCredentials []auth.Credential, jwtSignin
 	sbRoutes := brokerapi.NewWithCustomAuth(sb, logger, serviceBrokerAuthMiddleware.Handler)
 
 	sbRouter := sbRoutes.(*mux.Router)
+	sbRouter.Use(tracing.RouteMiddleware)
 	sbRouter.Use(LoggerMiddleware(logger))
+	sbRouter.Use(middlewares...)
 
-	rootRouter.NewRoute().Handler(sbRoutes)
+	rootRouter.NewRoute().Handler(tracing.Handler(sbRoutes))
 
 	return &API{rootRouter, logger}
 }
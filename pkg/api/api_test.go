@@ -2,6 +2,9 @@ package api
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -82,6 +85,7 @@ type apiRequest struct {
 	token       string
 	apiVersion  string
 	contentType string
+	clientCert  *x509.Certificate
 	body        bytes.Buffer
 }
 
@@ -100,6 +104,12 @@ func makeRequest(a *API, r apiRequest) *httptest.ResponseRecorder {
 	if r.contentType != "" {
 		request.Header.Add("Content-Type", r.contentType)
 	}
+	if r.clientCert != nil {
+		request.TLS = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{r.clientCert},
+			VerifiedChains:   [][]*x509.Certificate{{r.clientCert}},
+		}
+	}
 	a.ServeHTTP(recorder, request)
 	return recorder
 }
@@ -193,6 +203,38 @@ func TestAPI_SimpleRequests(t *testing.T) {
 	}
 }
 
+func TestAPI_ClientCertificate(t *testing.T) {
+	a, _ := setupServer()
+
+	rr := makeRequest(a, apiRequest{
+		method:     http.MethodGet,
+		path:       "/v2/catalog",
+		apiVersion: "2.14",
+		clientCert: &x509.Certificate{Subject: pkix.Name{CommonName: "platform"}},
+	})
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = makeRequest(a, apiRequest{
+		method:     http.MethodGet,
+		path:       "/v2/catalog",
+		apiVersion: "2.14",
+	})
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	for name, subject := range map[string]pkix.Name{
+		"without common name":           {Organization: []string{"Acme"}, OrganizationalUnit: []string{"Ops"}},
+		"common name not a label value": {CommonName: "Acme Platform"},
+	} {
+		rr = makeRequest(a, apiRequest{
+			method:     http.MethodGet,
+			path:       "/v2/catalog",
+			apiVersion: "2.14",
+			clientCert: &x509.Certificate{Subject: subject},
+		})
+		assert.Equal(t, http.StatusUnauthorized, rr.Code, name)
+	}
+}
+
 func TestAPI_Provision(t *testing.T) {
 	a, fsb := setupServer()
 	method := "PUT"
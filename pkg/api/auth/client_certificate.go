@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ClientCertificatePropertyName allows to query the HTTP context for the subject of the verified client certificate.
+// See Context() on http.Request.
+//
+//	func(w http.ResponseWriter, r *http.Request) {
+//	  subject := r.Context().Value(auth.ClientCertificatePropertyName).(string)
+//	  fmt.Fprintf(w, "Client certificate subject: '%s'\n", subject)
+//	}
+const ClientCertificatePropertyName contextKey = "client-certificate-subject"
+
+// ClientCertificate represents a mux middleware that, given a http.Request, checks whether the TLS connection
+// presented a client certificate that was verified against the configured CA bundle.
+// The verification itself is done by the TLS stack, see the `ClientCAs` of the tls.Config of the server.
+type ClientCertificate struct{}
+
+// Handler represents a mux.MiddlewareFunc
+func (c ClientCertificate) Handler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cert := verifiedClientCertificate(r)
+		if cert == nil {
+			unauthorized(w)
+			return
+		}
+		subject, ok := certificateSubject(cert)
+		if !ok {
+			unauthorized(w)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ClientCertificatePropertyName, subject)
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// verifiedClientCertificate returns the leaf certificate of the first verified client certificate chain.
+// It returns nil if the request was not made over TLS or if no client certificate could be verified.
+func verifiedClientCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return r.TLS.VerifiedChains[0][0]
+}
+
+// certificateSubject returns the common name of the certificate subject.
+// It returns false if the common name is empty or not a valid label value, as the principal is stored as label
+// on the instances it creates.
+func certificateSubject(cert *x509.Certificate) (string, bool) {
+	cn := cert.Subject.CommonName
+	if cn == "" || len(validation.IsValidLabelValue(cn)) > 0 {
+		return "", false
+	}
+	return cn, true
+}
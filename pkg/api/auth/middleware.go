@@ -16,9 +16,11 @@ type authenticationHandler interface {
 
 // AuthenticationMiddleware represents a mux middleware which, given an http.Request, can check whether
 // it's Authorization header contains a valid BearerToken or – if not – valid Basic Auth information.
+// Requests without an Authorization header are accepted if they were made with a verified TLS client certificate.
 type AuthenticationMiddleware struct {
-	bearerTokenAuth authenticationHandler
-	basicAuth       authenticationHandler
+	bearerTokenAuth       authenticationHandler
+	basicAuth             authenticationHandler
+	clientCertificateAuth authenticationHandler
 }
 
 type contextKey string
@@ -48,6 +50,16 @@ const (
 	//     }
 	//   }
 	AuthenticationMethodBasicAuth = "Basic"
+
+	// AuthenticationMethodClientCertificate should be used to compare with the value returned by AuthenticationMethodPropertyName:
+	//
+	//   func(w http.ResponseWriter, r *http.Request) {
+	//     method := r.Context().Value(auth.UserPropertyName);
+	//     if method == auth.AuthenticationMethodClientCertificate {
+	//       // TLS client certificate auth
+	//     }
+	//   }
+	AuthenticationMethodClientCertificate = "ClientCertificate"
 )
 
 var (
@@ -71,8 +83,9 @@ func New(credentials []Credential, keys *jwt.KeyRegister) AuthenticationMiddlewa
 	}
 
 	return AuthenticationMiddleware{
-		bearerTokenAuth: bearerToken,
-		basicAuth:       basic,
+		bearerTokenAuth:       bearerToken,
+		basicAuth:             basic,
+		clientCertificateAuth: ClientCertificate{},
 	}
 }
 
@@ -83,6 +96,10 @@ func (a AuthenticationMiddleware) Handler(handler http.Handler) http.Handler {
 
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
+			if verifiedClientCertificate(r) != nil {
+				a.handleClientCertificate(w, r, handler)
+				return
+			}
 			unauthorized(w)
 			return
 		}
@@ -118,6 +135,11 @@ func (a AuthenticationMiddleware) handleBearerToken(w http.ResponseWriter, r *ht
 	a.bearerTokenAuth.Handler(handler).ServeHTTP(w, r.WithContext(ctx))
 }
 
+func (a AuthenticationMiddleware) handleClientCertificate(w http.ResponseWriter, r *http.Request, handler http.Handler) {
+	ctx := context.WithValue(r.Context(), AuthenticationMethodPropertyName, AuthenticationMethodClientCertificate)
+	a.clientCertificateAuth.Handler(handler).ServeHTTP(w, r.WithContext(ctx))
+}
+
 func unauthorized(w http.ResponseWriter) {
 	failedAuthenticationCounter.Inc()
 	w.Header().Set("WWW-Authenticate", "Basic realm=\"Crossplane Service Broker\", charset=\"UTF-8\"")
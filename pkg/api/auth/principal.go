@@ -31,6 +31,8 @@ func PrincipalFromContext(ctx context.Context, cfg *config.Config) (Principal, e
 		return getPrincipalFromBasicAuth(ctx)
 	case authenticationMethodStr == AuthenticationMethodBearerToken:
 		return getPrincipalFromBearerToken(ctx, cfg)
+	case authenticationMethodStr == AuthenticationMethodClientCertificate:
+		return getPrincipalFromClientCertificate(ctx)
 	default:
 		return "", fmt.Errorf("unknown authorication format '%s'", authenticationMethodStr)
 	}
@@ -71,3 +73,18 @@ func getPrincipalFromBasicAuth(ctx context.Context) (Principal, error) {
 	}
 	return Principal(userNameStr), nil
 }
+
+// getPrincipalFromClientCertificate extracts the Principal on whose name the given http.Request was made based on the
+// subject of the verified TLS client certificate that was presented with the http.Request.
+func getPrincipalFromClientCertificate(ctx context.Context) (Principal, error) {
+	subject := ctx.Value(ClientCertificatePropertyName)
+	if subject == nil {
+		return "", fmt.Errorf("client certificate subject not set")
+	}
+
+	subjectStr, ok := subject.(string)
+	if !ok {
+		return "", fmt.Errorf("client certificate subject is not a string")
+	}
+	return Principal(subjectStr), nil
+}
@@ -45,6 +45,17 @@ func Test_BearerTokenPrincipal(t *testing.T) {
 	assert.Equal(t, givenUsername, string(actualPrincipal))
 }
 
+func Test_ClientCertificatePrincipal(t *testing.T) {
+	cfg := givenConfiguration(t, emptyEnv)
+	givenSubject := "expectedSubject"
+	ctx := givenAuthenticatedContext(AuthenticationMethodClientCertificate, ClientCertificatePropertyName, givenSubject)
+
+	actualPrincipal, err := PrincipalFromContext(ctx, cfg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, givenSubject, string(actualPrincipal))
+}
+
 func givenConfiguration(t *testing.T, env map[string]string) *config.Config {
 	cfg, err := config.ReadConfig(func(s string) string {
 		return env[s]
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi/v8/domain/apiresponses"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+
+	"github.com/vshn/crossplane-service-broker/pkg/api/auth"
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+)
+
+// limiterIdleTimeout defines after which time of inactivity the limiter of a principal is discarded.
+const limiterIdleTimeout = 10 * time.Minute
+
+var rateLimitedRequestsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "osb_rate_limited_requests_total",
+	Help: "The total number of service broker api requests which were rejected because the principal exceeded the rate limit.",
+}, []string{"principal"})
+
+// RateLimiter is a mux middleware which limits the request rate per Principal.
+// It must be used after authentication, as it relies on auth.PrincipalFromContext.
+type RateLimiter struct {
+	cfg    *config.Config
+	logger lager.Logger
+
+	mu        sync.Mutex
+	limiters  map[auth.Principal]*principalLimiter
+	lastSweep time.Time
+}
+
+type principalLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter returns a RateLimiter using the rate limit settings of the given config.
+func NewRateLimiter(cfg *config.Config, logger lager.Logger) *RateLimiter {
+	return &RateLimiter{
+		cfg:      cfg,
+		logger:   logger,
+		limiters: map[auth.Principal]*principalLimiter{},
+	}
+}
+
+// Handler represents a mux.MiddlewareFunc
+func (rl *RateLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if rl.cfg.RateLimit <= 0 {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		principal, err := auth.PrincipalFromContext(req.Context(), rl.cfg)
+		if err != nil {
+			// Requests without a principal are rejected by the authentication middleware already.
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		if !rl.allow(principal, time.Now()) {
+			rctx := reqcontext.NewReqContext(req.Context(), rl.logger, lager.Data{"principal": principal})
+			rctx.Logger.Info("rate-limit-exceeded")
+			rateLimitedRequestsCounter.WithLabelValues(string(principal)).Inc()
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", fmt.Sprintf("%d", int(math.Ceil(1/rl.cfg.RateLimit))))
+			w.WriteHeader(http.StatusTooManyRequests)
+			_ = json.NewEncoder(w).Encode(apiresponses.ErrorResponse{
+				Error:       "RateLimitExceeded",
+				Description: fmt.Sprintf("rate limit of %g requests per second exceeded (correlation-id: %q)", rl.cfg.RateLimit, rctx.CorrelationID),
+			})
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+func (rl *RateLimiter) allow(principal auth.Principal, now time.Time) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.Sub(rl.lastSweep) > limiterIdleTimeout {
+		for p, l := range rl.limiters {
+			if now.Sub(l.lastSeen) > limiterIdleTimeout {
+				delete(rl.limiters, p)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	l, ok := rl.limiters[principal]
+	if !ok {
+		l = &principalLimiter{
+			limiter: rate.NewLimiter(rate.Limit(rl.cfg.RateLimit), rl.cfg.RateLimitBurst),
+		}
+		rl.limiters[principal] = l
+	}
+	l.lastSeen = now
+	return l.limiter.AllowN(now, 1)
+}
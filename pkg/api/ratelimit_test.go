@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pascaldekloe/jwt"
+	"github.com/pivotal-cf/brokerapi/v8/domain/apiresponses"
+	"github.com/pivotal-cf/brokerapi/v8/fakes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vshn/crossplane-service-broker/pkg/api/auth"
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+)
+
+func setupRateLimitedServer(t *testing.T, rateLimit float64, burst int) *API {
+	cfg, err := config.ReadConfig(func(s string) string {
+		return map[string]string{
+			config.EnvServiceIDs: "test",
+			config.EnvUsername:   username,
+			config.EnvPassword:   password,
+			config.EnvNamespace:  "test",
+		}[s]
+	})
+	require.NoError(t, err)
+	cfg.RateLimit = rateLimit
+	cfg.RateLimitBurst = burst
+
+	logger := lager.NewLogger("test")
+	return New(&fakes.AutoFakeServiceBroker{},
+		auth.SingleCredential(username, password),
+		&jwt.KeyRegister{Secrets: [][]byte{[]byte("test")}},
+		logger,
+		NewRateLimiter(cfg, logger).Handler)
+}
+
+func TestAPI_RateLimit(t *testing.T) {
+	a := setupRateLimitedServer(t, 0.001, 2)
+	r := apiRequest{
+		method:     http.MethodGet,
+		path:       "/v2/catalog",
+		apiVersion: "2.14",
+		username:   username,
+		password:   password,
+	}
+
+	assert.Equal(t, http.StatusOK, makeRequest(a, r).Code)
+	assert.Equal(t, http.StatusOK, makeRequest(a, r).Code)
+
+	rr := makeRequest(a, r)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+	errResp := apiresponses.ErrorResponse{}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&errResp))
+	assert.Equal(t, "RateLimitExceeded", errResp.Error)
+
+	// Other principals have their own limit
+	r.username = ""
+	r.password = ""
+	r.token = token
+	assert.Equal(t, http.StatusOK, makeRequest(a, r).Code)
+}
+
+func TestAPI_RateLimitDisabled(t *testing.T) {
+	a := setupRateLimitedServer(t, 0, 0)
+	r := apiRequest{
+		method:     http.MethodGet,
+		path:       "/v2/catalog",
+		apiVersion: "2.14",
+		username:   username,
+		password:   password,
+	}
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, http.StatusOK, makeRequest(a, r).Code)
+	}
+}
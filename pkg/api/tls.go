@@ -0,0 +1,151 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// tlsCheckInterval defines how often the files are checked for modifications at most.
+const tlsCheckInterval = 10 * time.Second
+
+// CertificateReloader serves a TLS certificate and an optional client CA bundle from disk.
+// The files are checked for modifications on TLS handshakes, at most once per tlsCheckInterval, and reloaded
+// if they changed, which allows rotating certificates (e.g. by cert-manager) without restarting the broker.
+type CertificateReloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	logger       lager.Logger
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+// NewCertificateReloader loads the given files and returns a CertificateReloader.
+// clientCAFile may be empty, in which case client certificates are not verified.
+func NewCertificateReloader(certFile, keyFile, clientCAFile string, logger lager.Logger) (*CertificateReloader, error) {
+	cr := &CertificateReloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		logger:       logger,
+	}
+	if err := cr.reload(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// TLSConfig returns a tls.Config which uses the reloaded certificate and client CA bundle.
+// If a client CA bundle is configured, client certificates are verified if given, but not required,
+// so that clients can still authenticate using the Authorization header.
+func (cr *CertificateReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(_ *tls.ClientHelloInfo) (*tls.Config, error) {
+			cr.reloadIfModified(time.Now())
+
+			cr.mu.RLock()
+			defer cr.mu.RUnlock()
+
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cr.cert},
+			}
+			if cr.clientCAs != nil {
+				cfg.ClientCAs = cr.clientCAs
+				cfg.ClientAuth = tls.VerifyClientCertIfGiven
+			}
+			return cfg, nil
+		},
+	}
+}
+
+func (cr *CertificateReloader) reloadIfModified(now time.Time) {
+	cr.mu.Lock()
+	due := now.Sub(cr.lastCheck) >= tlsCheckInterval
+	if due {
+		cr.lastCheck = now
+	}
+	cr.mu.Unlock()
+	if !due {
+		return
+	}
+
+	modTime, err := cr.latestModTime()
+	if err != nil {
+		cr.logger.Error("stat-tls-files", err)
+		return
+	}
+
+	cr.mu.RLock()
+	unchanged := !modTime.After(cr.modTime)
+	cr.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	// Errors are only logged, the previously loaded certificate stays in use.
+	if err := cr.reload(); err != nil {
+		cr.logger.Error("reload-tls-files", err)
+		return
+	}
+	cr.logger.Info("reloaded-tls-files", lager.Data{"modification-time": modTime})
+}
+
+func (cr *CertificateReloader) reload() error {
+	modTime, err := cr.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load certificate '%s' and key '%s': %w", cr.certFile, cr.keyFile, err)
+	}
+
+	var clientCAs *x509.CertPool
+	if cr.clientCAFile != "" {
+		pem, err := os.ReadFile(cr.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("unable to read client CA bundle '%s': %w", cr.clientCAFile, err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("client CA bundle '%s' does not contain any PEM encoded certificates", cr.clientCAFile)
+		}
+	}
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.cert = &cert
+	cr.clientCAs = clientCAs
+	cr.modTime = modTime
+	return nil
+}
+
+func (cr *CertificateReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{cr.certFile, cr.keyFile, cr.clientCAFile} {
+		if f == "" {
+			continue
+		}
+		fi, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unable to stat '%s': %w", f, err)
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
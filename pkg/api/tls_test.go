@@ -0,0 +1,139 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	caFile := filepath.Join(dir, "ca.crt")
+
+	writeCertificate(t, certFile, keyFile, "first")
+	writeCertificate(t, caFile, filepath.Join(dir, "ca.key"), "ca")
+
+	cr, err := NewCertificateReloader(certFile, keyFile, caFile, lager.NewLogger("test"))
+	require.NoError(t, err)
+
+	cfg, err := cr.TLSConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, tls.VerifyClientCertIfGiven, cfg.ClientAuth)
+	assert.Equal(t, "first", leafCommonName(t, cfg))
+
+	writeCertificate(t, certFile, keyFile, "second")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+
+	cfg, err = cr.TLSConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "first", leafCommonName(t, cfg), "the files must not be checked again within tlsCheckInterval")
+
+	cr.reloadIfModified(time.Now().Add(tlsCheckInterval))
+	cfg, err = cr.TLSConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, "second", leafCommonName(t, cfg))
+}
+
+func TestCertificateReloader_Rotation(t *testing.T) {
+	// Mounted Secrets are rotated by pointing the ..data symlink to a new directory.
+	dir := t.TempDir()
+	for _, version := range []string{"v1", "v2"} {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, version), 0700))
+		writeCertificate(t, filepath.Join(dir, version, "tls.crt"), filepath.Join(dir, version, "tls.key"), version)
+	}
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "v2", "tls.crt"), future, future))
+	require.NoError(t, os.Symlink("v1", filepath.Join(dir, "..data")))
+	for _, f := range []string{"tls.crt", "tls.key"} {
+		require.NoError(t, os.Symlink(filepath.Join("..data", f), filepath.Join(dir, f)))
+	}
+
+	cr, err := NewCertificateReloader(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), "", lager.NewLogger("test"))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", handshake(t, cr.TLSConfig()))
+
+	require.NoError(t, os.Symlink("v2", filepath.Join(dir, "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+	cr.lastCheck = time.Time{}
+	assert.Equal(t, "v2", handshake(t, cr.TLSConfig()), "the rotated certificate must be served")
+}
+
+func TestCertificateReloader_WithoutClientCA(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeCertificate(t, certFile, keyFile, "server")
+
+	cr, err := NewCertificateReloader(certFile, keyFile, "", lager.NewLogger("test"))
+	require.NoError(t, err)
+
+	cfg, err := cr.TLSConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	assert.Equal(t, tls.NoClientCert, cfg.ClientAuth)
+	assert.Nil(t, cfg.ClientCAs)
+}
+
+func TestCertificateReloader_InvalidFiles(t *testing.T) {
+	dir := t.TempDir()
+	_, err := NewCertificateReloader(filepath.Join(dir, "missing.crt"), filepath.Join(dir, "missing.key"), "", lager.NewLogger("test"))
+	assert.Error(t, err)
+}
+
+func writeCertificate(t *testing.T, certFile, keyFile, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+}
+
+// handshake connects to a server using cfg and returns the common name of the certificate it presented.
+func handshake(t *testing.T, cfg *tls.Config) string {
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	go func() {
+		defer serverConn.Close()
+		_ = tls.Server(serverConn, cfg).Handshake()
+	}()
+
+	client := tls.Client(clientConn, &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, client.Handshake())
+	return client.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func leafCommonName(t *testing.T, cfg *tls.Config) string {
+	require.Len(t, cfg.Certificates, 1)
+	leaf, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+	require.NoError(t, err)
+	return leaf.Subject.CommonName
+}
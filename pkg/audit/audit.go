@@ -0,0 +1,139 @@
+// Package audit records state-changing service broker operations to a dedicated sink.
+package audit
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+
+	"github.com/vshn/crossplane-service-broker/pkg/api/auth"
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+	"github.com/vshn/crossplane-service-broker/pkg/logging"
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+)
+
+// Operations which are audited.
+const (
+	OperationProvision   = "provision"
+	OperationUpdate      = "update"
+	OperationDeprovision = "deprovision"
+	OperationBind        = "bind"
+	OperationUnbind      = "unbind"
+)
+
+// Outcomes of an audited operation.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Event describes a single state-changing operation.
+type Event struct {
+	Time          time.Time   `json:"time"`
+	Operation     string      `json:"operation"`
+	Principal     string      `json:"principal"`
+	OnBehalfOf    string      `json:"originating_identity,omitempty"`
+	CorrelationID string      `json:"correlation_id"`
+	TraceID       string      `json:"trace_id,omitempty"`
+	InstanceID    string      `json:"instance_id"`
+	BindingID     string      `json:"binding_id,omitempty"`
+	ServiceID     string      `json:"service_id,omitempty"`
+	PlanID        string      `json:"plan_id,omitempty"`
+	PreviousPlan  string      `json:"previous_plan_id,omitempty"`
+	Parameters    interface{} `json:"parameters,omitempty"`
+	Outcome       string      `json:"outcome"`
+	Code          string      `json:"code"`
+	Error         string      `json:"error,omitempty"`
+}
+
+// Sink writes audit events.
+type Sink interface {
+	Write(rctx *reqcontext.ReqContext, e Event) error
+}
+
+// EventRecorder records Kubernetes Events on the composite of an instance, or on the namespace of the service broker.
+type EventRecorder interface {
+	RecordInstanceEvent(rctx *reqcontext.ReqContext, instanceID, planID, eventType, reason, message string) error
+	RecordBrokerEvent(rctx *reqcontext.ReqContext, eventType, reason, message string) error
+}
+
+// Log completes audit events with request data and writes them to a Sink.
+type Log struct {
+	cfg  *config.Config
+	sink Sink
+}
+
+// New returns a Log writing to the sink configured in cfg.
+// It returns nil if no audit sink is configured. Kubernetes Events are recorded using the given recorder.
+// Events which can't be delivered to a webhook are logged to logger.
+func New(cfg *config.Config, recorder EventRecorder, logger lager.Logger) (*Log, error) {
+	var sink Sink
+	switch {
+	case cfg.AuditSink == "":
+		return nil, nil
+	case cfg.AuditSink == config.AuditSinkKubernetesEvents:
+		sink = NewEventSink(recorder)
+	default:
+		u, err := url.Parse(cfg.AuditSink)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme == "file" {
+			sink, err = NewFileSink(u.Path)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			sink = NewWebhookSink(u.String(), logger)
+		}
+	}
+	return NewLog(cfg, sink), nil
+}
+
+// NewLog returns a Log writing to sink.
+func NewLog(cfg *config.Config, sink Sink) *Log {
+	return &Log{
+		cfg:  cfg,
+		sink: sink,
+	}
+}
+
+// Record adds the principal, originating identity, correlation ID and time to e and writes it to the sink.
+// Parameters are redacted. Failures are logged, but don't fail the audited operation.
+// Record does nothing if l is nil.
+func (l *Log) Record(rctx *reqcontext.ReqContext, e Event) {
+	if l == nil {
+		return
+	}
+
+	principal, err := auth.PrincipalFromContext(rctx.Context, l.cfg)
+	if err != nil {
+		principal = "unknown"
+	}
+	e.Time = time.Now().UTC()
+	e.Principal = string(principal)
+	e.OnBehalfOf = rctx.OriginatingIdentity.String()
+	e.CorrelationID = rctx.CorrelationID
+	e.TraceID = rctx.TraceID
+	if e.Parameters != nil {
+		e.Parameters = logging.Redact(e.Parameters)
+	}
+
+	if err := l.sink.Write(rctx, e); err != nil {
+		rctx.Logger.Error("write-audit-event", err, lager.Data{"operation": e.Operation})
+	}
+}
+
+// Close stops the sink after delivering the queued events, if it queues events.
+// Close does nothing if l is nil.
+func (l *Log) Close(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	if c, ok := l.sink.(interface{ Close(context.Context) error }); ok {
+		return c.Close(ctx)
+	}
+	return nil
+}
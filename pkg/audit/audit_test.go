@@ -0,0 +1,285 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pascaldekloe/jwt"
+	"github.com/pivotal-cf/brokerapi/v8/middlewares"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/vshn/crossplane-service-broker/pkg/api/auth"
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+)
+
+type fakeRecorder struct {
+	instanceID, planID, eventType, reason, message string
+	// instanceErr is returned when recording an event on an instance.
+	instanceErr error
+	brokerEvent bool
+}
+
+func (f *fakeRecorder) RecordInstanceEvent(_ *reqcontext.ReqContext, instanceID, planID, eventType, reason, message string) error {
+	if f.instanceErr != nil {
+		return f.instanceErr
+	}
+	f.instanceID, f.planID, f.eventType, f.reason, f.message = instanceID, planID, eventType, reason, message
+	return nil
+}
+
+func (f *fakeRecorder) RecordBrokerEvent(_ *reqcontext.ReqContext, eventType, reason, message string) error {
+	f.brokerEvent, f.eventType, f.reason, f.message = true, eventType, reason, message
+	return nil
+}
+
+func givenRequestContext() *reqcontext.ReqContext {
+	ctx := context.WithValue(context.Background(), middlewares.CorrelationIDKey, "corrid")
+	ctx = context.WithValue(ctx, auth.AuthenticationMethodPropertyName, auth.AuthenticationMethodBearerToken)
+	ctx = context.WithValue(ctx, auth.TokenPropertyName, &jwt.Claims{Set: map[string]interface{}{"sub": "alice"}})
+	return reqcontext.NewReqContext(ctx, lager.NewLogger("test"), nil)
+}
+
+func givenRequestContextWithIdentity(header string) *reqcontext.ReqContext {
+	rctx := givenRequestContext()
+	return reqcontext.NewReqContext(context.WithValue(rctx.Context, middlewares.OriginatingIdentityKey, header), lager.NewLogger("test"), nil)
+}
+
+func givenEvent() Event {
+	return Event{
+		Operation:  OperationProvision,
+		InstanceID: "1",
+		ServiceID:  "redis",
+		PlanID:     "redis-small",
+		Parameters: json.RawMessage(`{"size":"small","password":"secret"}`),
+		Outcome:    OutcomeSuccess,
+		Code:       "2xx",
+	}
+}
+
+func TestLog_FileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	cfg := &config.Config{UsernameClaim: "sub", AuditSink: "file://" + path}
+	l, err := New(cfg, nil, nil)
+	require.NoError(t, err)
+
+	l.Record(givenRequestContext(), givenEvent())
+	l.Record(givenRequestContext(), givenEvent())
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	require.Len(t, lines, 2)
+
+	e := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &e))
+	assert.Equal(t, "provision", e["operation"])
+	assert.Equal(t, "alice", e["principal"])
+	assert.Equal(t, "corrid", e["correlation_id"])
+	assert.Equal(t, "1", e["instance_id"])
+	assert.Equal(t, "redis-small", e["plan_id"])
+	assert.Equal(t, map[string]interface{}{"size": "small", "password": "*REDACTED*"}, e["parameters"])
+	assert.Equal(t, "success", e["outcome"])
+	assert.NotEmpty(t, e["time"])
+}
+
+func TestLog_WebhookSink(t *testing.T) {
+	received := make(chan Event, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e := Event{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&e))
+		received <- e
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	l, err := New(&config.Config{UsernameClaim: "sub", AuditSink: srv.URL}, nil, lager.NewLogger("test"))
+	require.NoError(t, err)
+
+	l.Record(givenRequestContext(), givenEvent())
+
+	e := <-received
+	assert.Equal(t, "alice", e.Principal)
+	assert.Equal(t, OperationProvision, e.Operation)
+	assert.Equal(t, map[string]interface{}{"size": "small", "password": "*REDACTED*"}, e.Parameters)
+	assert.NoError(t, l.Close(context.Background()))
+}
+
+func TestWebhookSink_Retry(t *testing.T) {
+	attempts := int32(0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	logs := &bytes.Buffer{}
+	s := givenWebhookSink(srv.URL, logs)
+	require.NoError(t, s.Write(givenRequestContext(), givenEvent()))
+	require.NoError(t, s.Close(context.Background()))
+
+	assert.EqualValues(t, 2, atomic.LoadInt32(&attempts))
+	assert.Empty(t, logs.String(), "a delivered event must not be logged")
+}
+
+func TestWebhookSink_Undeliverable(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	logs := &bytes.Buffer{}
+	s := givenWebhookSink(srv.URL, logs)
+
+	start := time.Now()
+	require.NoError(t, s.Write(givenRequestContext(), givenEvent()))
+	assert.Less(t, time.Since(start), time.Second, "writing must not wait for the webhook")
+	close(release)
+	require.NoError(t, s.Close(context.Background()))
+
+	assert.Contains(t, logs.String(), `"message":"test.deliver-audit-event"`)
+	assert.Contains(t, logs.String(), `"error":"audit webhook responded with status 500"`)
+	assert.Contains(t, logs.String(), `"instance_id":"1"`, "undelivered events must be logged with their content")
+}
+
+func TestWebhookSink_QueueFull(t *testing.T) {
+	s := &WebhookSink{queue: make(chan webhookEvent, 1)}
+	require.NoError(t, s.Write(givenRequestContext(), givenEvent()))
+	assert.ErrorContains(t, s.Write(givenRequestContext(), givenEvent()), "audit webhook queue is full, dropped event {")
+}
+
+func TestWebhookSink_WriteAfterClose(t *testing.T) {
+	s := givenWebhookSink("http://127.0.0.1:0", &bytes.Buffer{})
+	require.NoError(t, s.Close(context.Background()))
+	assert.ErrorContains(t, s.Write(givenRequestContext(), givenEvent()), "audit webhook sink is closed, dropped event {")
+	assert.NoError(t, s.Close(context.Background()), "closing again must not panic")
+}
+
+func TestWebhookSink_CloseExpired(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	logs := &syncBuffer{}
+	logger := lager.NewLogger("test")
+	logger.RegisterSink(lager.NewWriterSink(logs, lager.ERROR))
+	s := NewWebhookSink(srv.URL, logger)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.Write(givenRequestContext(), givenEvent()))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.Close(ctx), context.DeadlineExceeded)
+
+	// The first event is being delivered while the others are still queued.
+	assert.Contains(t, logs.String(), `"message":"test.close-audit-webhook"`)
+	assert.Contains(t, logs.String(), `"dropped-events":2`)
+	assert.Contains(t, logs.String(), `"instance_id":"1"`, "dropped events must be logged with their content")
+}
+
+// syncBuffer is a bytes.Buffer which can be written by the delivering goroutine while the test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func givenWebhookSink(url string, logs *bytes.Buffer) *WebhookSink {
+	logger := lager.NewLogger("test")
+	logger.RegisterSink(lager.NewWriterSink(logs, lager.ERROR))
+	s := NewWebhookSink(url, logger)
+	s.retryDelay = time.Millisecond
+	return s
+}
+
+func TestLog_EventSink(t *testing.T) {
+	r := &fakeRecorder{}
+	l, err := New(&config.Config{UsernameClaim: "sub", AuditSink: config.AuditSinkKubernetesEvents}, r, nil)
+	require.NoError(t, err)
+
+	e := givenEvent()
+	e.Operation = OperationUnbind
+	e.BindingID = "b1"
+	e.Outcome = OutcomeFailure
+	e.Code = "410"
+	e.Error = "gone"
+	l.Record(givenRequestContext(), e)
+
+	assert.Equal(t, &fakeRecorder{
+		instanceID: "1",
+		planID:     "redis-small",
+		eventType:  corev1.EventTypeWarning,
+		reason:     "Audit",
+		message:    `unbind by "alice" of binding "b1" failed with 410 gone (correlation-id: "corrid")`,
+	}, r, nil)
+}
+
+func TestLog_EventSink_WithoutComposite(t *testing.T) {
+	r := &fakeRecorder{instanceErr: errors.New(`compositeredisinstances.syn.tools "1" not found`)}
+	l, err := New(&config.Config{UsernameClaim: "sub", AuditSink: config.AuditSinkKubernetesEvents}, r, nil)
+	require.NoError(t, err)
+
+	e := givenEvent()
+	e.Outcome = OutcomeFailure
+	e.Code = "500"
+	e.Error = "internal"
+	l.Record(givenRequestContext(), e)
+
+	assert.True(t, r.brokerEvent, "the event must be recorded on the namespace of the broker")
+	assert.Equal(t, corev1.EventTypeWarning, r.eventType)
+	assert.Equal(t, `instance "1": provision by "alice" failed with 500 internal (correlation-id: "corrid")`, r.message)
+}
+
+func TestNew_Disabled(t *testing.T) {
+	l, err := New(&config.Config{}, nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, l)
+	// Recording to a nil log must be a no-op.
+	l.Record(givenRequestContext(), givenEvent())
+}
+
+func TestLog_OriginatingIdentity(t *testing.T) {
+	r := &fakeRecorder{}
+	l, err := New(&config.Config{UsernameClaim: "sub", AuditSink: config.AuditSinkKubernetesEvents}, r, nil)
+	require.NoError(t, err)
+
+	// {"user_id":"683ea748"}
+	l.Record(givenRequestContextWithIdentity("cloudfoundry eyJ1c2VyX2lkIjoiNjgzZWE3NDgifQ=="), givenEvent())
+
+	assert.Equal(t, `provision by "alice" on behalf of "cloudfoundry:683ea748" succeeded (correlation-id: "corrid")`, r.message)
+}
@@ -0,0 +1,230 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/hashicorp/go-cleanhttp"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+)
+
+const (
+	// webhookTimeout limits how long delivering an audit event to a webhook may take.
+	webhookTimeout = 5 * time.Second
+	// webhookQueueSize is the number of audit events which may wait for delivery to a webhook.
+	webhookQueueSize = 1000
+	// webhookAttempts is how often delivering an audit event is tried.
+	webhookAttempts = 3
+	// webhookRetryDelay is the delay before the first retry, which doubles with every further retry.
+	webhookRetryDelay = time.Second
+)
+
+// FileSink appends audit events as JSON lines to a file.
+type FileSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// NewFileSink opens (or creates) the file at path for appending.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open audit log: %w", err)
+	}
+	return &FileSink{f: f}, nil
+}
+
+// Write implements Sink.
+func (s *FileSink) Write(_ *reqcontext.ReqContext, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.f.Write(append(b, '\n'))
+	return err
+}
+
+// WebhookSink posts audit events as JSON to a URL.
+// Events are queued and delivered in the background, so a slow webhook doesn't delay the audited operations.
+// Failed deliveries are retried. Events which can't be delivered, or don't fit into the queue, are logged with their content.
+type WebhookSink struct {
+	url        string
+	client     *http.Client
+	logger     lager.Logger
+	queue      chan webhookEvent
+	done       chan struct{}
+	retryDelay time.Duration
+
+	// mu guards closing the queue against concurrent writes.
+	mu     sync.RWMutex
+	closed bool
+}
+
+type webhookEvent struct {
+	event Event
+	body  []byte
+}
+
+// NewWebhookSink returns a sink posting to url. It starts delivering events right away, until Close is called.
+func NewWebhookSink(url string, logger lager.Logger) *WebhookSink {
+	c := cleanhttp.DefaultClient()
+	c.Timeout = webhookTimeout
+	s := &WebhookSink{
+		url:        url,
+		client:     c,
+		logger:     logger,
+		queue:      make(chan webhookEvent, webhookQueueSize),
+		done:       make(chan struct{}),
+		retryDelay: webhookRetryDelay,
+	}
+	go s.run()
+	return s
+}
+
+// Write implements Sink. It only queues the event and fails if the queue is full or the sink is closed.
+func (s *WebhookSink) Write(_ *reqcontext.ReqContext, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return fmt.Errorf("audit webhook sink is closed, dropped event %s", b)
+	}
+	select {
+	case s.queue <- webhookEvent{event: e, body: b}:
+		return nil
+	default:
+		return fmt.Errorf("audit webhook queue is full, dropped event %s", b)
+	}
+}
+
+// Close delivers the queued events and stops the sink. Events which can't be delivered within ctx are logged.
+// Writing to a closed sink fails.
+func (s *WebhookSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+	}
+	dropped := 0
+	for e := range s.queue {
+		s.logger.Error("drop-audit-event", ctx.Err(), lager.Data{
+			"operation":      e.event.Operation,
+			"correlation-id": e.event.CorrelationID,
+			"event":          json.RawMessage(e.body),
+		})
+		dropped++
+	}
+	s.logger.Error("close-audit-webhook", ctx.Err(), lager.Data{"dropped-events": dropped})
+	return ctx.Err()
+}
+
+func (s *WebhookSink) run() {
+	defer close(s.done)
+	for e := range s.queue {
+		if err := s.deliver(e.body); err != nil {
+			s.logger.Error("deliver-audit-event", err, lager.Data{
+				"operation":      e.event.Operation,
+				"correlation-id": e.event.CorrelationID,
+				"event":          json.RawMessage(e.body),
+			})
+		}
+	}
+}
+
+// deliver posts an event, retrying with exponential backoff.
+func (s *WebhookSink) deliver(body []byte) error {
+	var err error
+	delay := s.retryDelay
+	for attempt := 0; attempt < webhookAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if err = s.post(body); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (s *WebhookSink) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("audit webhook responded with status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// EventSink records audit events as Kubernetes Events on the composite of the instance.
+// If there is no composite, the event is recorded on the namespace of the service broker.
+type EventSink struct {
+	recorder EventRecorder
+}
+
+// NewEventSink returns a sink recording events using recorder.
+func NewEventSink(recorder EventRecorder) *EventSink {
+	return &EventSink{recorder: recorder}
+}
+
+// Write implements Sink.
+func (s *EventSink) Write(rctx *reqcontext.ReqContext, e Event) error {
+	eventType := corev1.EventTypeNormal
+	if e.Outcome != OutcomeSuccess {
+		eventType = corev1.EventTypeWarning
+	}
+	err := s.recorder.RecordInstanceEvent(rctx, e.InstanceID, e.PlanID, eventType, "Audit", Message(e))
+	if err == nil {
+		return nil
+	}
+	// The composite doesn't exist if provisioning failed, or the plan is unknown.
+	rctx.Logger.Debug("record-audit-event-on-instance", lager.Data{"error": err.Error()})
+	return s.recorder.RecordBrokerEvent(rctx, eventType, "Audit", fmt.Sprintf("instance %q: %s", e.InstanceID, Message(e)))
+}
+
+// Message returns a human readable summary of e.
+func Message(e Event) string {
+	msg := fmt.Sprintf("%s by %q", e.Operation, e.Principal)
+	if e.OnBehalfOf != "" {
+		msg += fmt.Sprintf(" on behalf of %q", e.OnBehalfOf)
+	}
+	if e.BindingID != "" {
+		msg += fmt.Sprintf(" of binding %q", e.BindingID)
+	}
+	if e.Outcome == OutcomeSuccess {
+		msg += " succeeded"
+	} else {
+		msg += fmt.Sprintf(" failed with %s %s", e.Code, e.Error)
+	}
+	return msg + fmt.Sprintf(" (correlation-id: %q)", e.CorrelationID)
+}
@@ -0,0 +1,19 @@
+package brokerapi
+
+import (
+	"github.com/vshn/crossplane-service-broker/pkg/audit"
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+)
+
+// audit completes e with the outcome derived from err and records it to the audit log.
+func (b BrokerAPI) audit(rctx *reqcontext.ReqContext, e audit.Event, err error) {
+	if b.auditLog == nil {
+		return
+	}
+	e.Code, e.Error = outcome(err)
+	e.Outcome = audit.OutcomeSuccess
+	if err != nil {
+		e.Outcome = audit.OutcomeFailure
+	}
+	b.auditLog.Record(rctx, e)
+}
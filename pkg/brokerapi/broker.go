@@ -4,13 +4,15 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strings"
+	"sync/atomic"
 
 	"code.cloudfoundry.org/lager"
 	xrv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/pivotal-cf/brokerapi/v8/domain"
 	"github.com/pivotal-cf/brokerapi/v8/domain/apiresponses"
-	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/utils/pointer"
 
 	"github.com/vshn/crossplane-service-broker/pkg/crossplane"
 	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
@@ -26,15 +28,22 @@ var (
 // Broker implements the service broker
 type Broker struct {
 	cp           *crossplane.Crossplane
-	planComparer crossplane.PlanUpdateChecker
+	planComparer *atomic.Pointer[crossplane.PlanUpdateChecker]
 }
 
 // NewBroker sets up a new broker.
 func NewBroker(cp *crossplane.Crossplane, pc crossplane.PlanUpdateChecker) *Broker {
-	return &Broker{
+	b := &Broker{
 		cp:           cp,
-		planComparer: pc,
+		planComparer: &atomic.Pointer[crossplane.PlanUpdateChecker]{},
 	}
+	b.SetPlanUpdateChecker(pc)
+	return b
+}
+
+// SetPlanUpdateChecker atomically replaces the rules plan updates are checked against.
+func (b Broker) SetPlanUpdateChecker(pc crossplane.PlanUpdateChecker) {
+	b.planComparer.Store(&pc)
 }
 
 // Services retrieves registered services and plans.
@@ -47,33 +56,57 @@ func (b Broker) Services(rctx *reqcontext.ReqContext) ([]domain.Service, error)
 	}
 
 	for _, xrd := range xrds {
-		plans, err := b.servicePlans(rctx, []string{xrd.Labels.ServiceID})
+		plans, err := b.servicePlans(rctx, xrd)
 		if err != nil {
 			rctx.Logger.Error("plan retrieval failed", err, lager.Data{"serviceId": xrd.Labels.ServiceID})
 		}
 
-		services = append(services, newService(xrd, plans, rctx.Logger))
+		dashboardClient, err := b.cp.DashboardClient(rctx, xrd)
+		if err != nil {
+			rctx.Logger.Error("dashboard-client", err, lager.Data{"serviceId": xrd.Labels.ServiceID})
+		}
+
+		services = append(services, newService(xrd, plans, dashboardClient, rctx.Logger))
 	}
 
 	return services, nil
 }
 
-// servicePlans retrieves a combined view of services and their plans.
-func (b Broker) servicePlans(rctx *reqcontext.ReqContext, serviceIDs []string) ([]domain.ServicePlan, error) {
+// servicePlans retrieves the plans of a service.
+// If the service is updatable, a plan is marked as updatable if it can be updated to any other plan of the service.
+func (b Broker) servicePlans(rctx *reqcontext.ReqContext, xrd *crossplane.ServiceXRD) ([]domain.ServicePlan, error) {
 	plans := make([]domain.ServicePlan, 0)
 
-	compositions, err := b.cp.Plans(rctx, serviceIDs)
+	compositions, err := b.cp.Plans(rctx, []string{xrd.Labels.ServiceID})
 	if err != nil {
 		return nil, err
 	}
+	for _, c := range compositions {
+		c.UpdateTargets = xrd.UpdateTargets(c)
+	}
 
+	pc := b.planComparer.Load()
 	for _, c := range compositions {
-		plans = append(plans, newServicePlan(c, rctx.Logger))
+		plan := newServicePlan(c, rctx.Logger)
+		if xrd.Labels.Updatable {
+			plan.PlanUpdatable = pointer.BoolPtr(planUpdatable(*pc, c, compositions))
+		}
+		plans = append(plans, plan)
 	}
 
 	return plans, nil
 }
 
+// planUpdatable returns true if the plan can be updated to any of the other plans.
+func planUpdatable(pc crossplane.PlanUpdateChecker, plan *crossplane.Plan, plans []*crossplane.Plan) bool {
+	for _, p := range plans {
+		if p.Composition.Name != plan.Composition.Name && pc.AllowUpdate(*plan, *p) {
+			return true
+		}
+	}
+	return false
+}
+
 // Provision creates a new service instance.
 func (b Broker) Provision(rctx *reqcontext.ReqContext, instanceID, planID string, params json.RawMessage) (domain.ProvisionedServiceSpec, error) {
 	res := domain.ProvisionedServiceSpec{}
@@ -82,6 +115,7 @@ func (b Broker) Provision(rctx *reqcontext.ReqContext, instanceID, planID string
 	if err != nil {
 		return res, err
 	}
+	planResolved(rctx, plan)
 
 	instance, exists, err := b.cp.Instance(rctx, instanceID, plan)
 	if err != nil {
@@ -92,11 +126,19 @@ func (b Broker) Provision(rctx *reqcontext.ReqContext, instanceID, planID string
 		// only instances without any parameters are considered to be equal to another (i.e. existing)
 		if params == nil {
 			res.AlreadyExists = true
+			res.DashboardURL = b.dashboardURL(rctx, plan, instanceID, instance.GetClusterName())
 			return res, nil
 		}
 		return res, apiresponses.ErrInstanceAlreadyExists
 	}
 
+	if err := b.cp.CheckInstanceQuota(rctx, plan); err != nil {
+		return res, err
+	}
+	if err := b.cp.CheckClusterCapacity(rctx, plan, nil); err != nil {
+		return res, err
+	}
+
 	ap := map[string]interface{}{}
 	if params != nil {
 		ap, err = b.validateParams(rctx, instance, plan.Labels.ServiceName, params)
@@ -111,9 +153,20 @@ func (b Broker) Provision(rctx *reqcontext.ReqContext, instanceID, planID string
 	}
 
 	res.IsAsync = true
+	res.DashboardURL = b.dashboardURL(rctx, plan, instanceID, plan.Composition.Labels[crossplane.ClusterLabel])
 	return res, nil
 }
 
+// dashboardURL returns the dashboard URL of an instance. Failing to render it is logged and results in an empty URL,
+// as the dashboard is optional.
+func (b Broker) dashboardURL(rctx *reqcontext.ReqContext, plan *crossplane.Plan, instanceID, cluster string) string {
+	u, err := b.cp.DashboardURL(rctx, plan, instanceID, cluster)
+	if err != nil {
+		rctx.Logger.Error("render-dashboard-url", err, lager.Data{"instance-id": instanceID})
+	}
+	return u
+}
+
 // Deprovision removes a provisioned instance.
 func (b Broker) Deprovision(rctx *reqcontext.ReqContext, instanceID, planID string) (domain.DeprovisionServiceSpec, error) {
 	res := domain.DeprovisionServiceSpec{
@@ -140,12 +193,12 @@ func (b Broker) Deprovision(rctx *reqcontext.ReqContext, instanceID, planID stri
 }
 
 // Bind creates a binding between a provisioned service instance and an application.
-func (b Broker) Bind(rctx *reqcontext.ReqContext, instanceID, bindingID, planID string, asyncAllowed bool) (domain.Binding, error) {
+func (b Broker) Bind(rctx *reqcontext.ReqContext, instanceID, bindingID, planID string, rawParameters json.RawMessage, asyncAllowed bool) (domain.Binding, error) {
 	res := domain.Binding{
 		IsAsync: asyncAllowed,
 	}
 
-	_, instance, err := b.getPlanInstance(rctx, planID, instanceID)
+	p, instance, err := b.getPlanInstance(rctx, planID, instanceID)
 	if err != nil {
 		return res, err
 	}
@@ -158,16 +211,67 @@ func (b Broker) Bind(rctx *reqcontext.ReqContext, instanceID, bindingID, planID
 		return res, err
 	}
 
-	creds, err := sb.Bind(rctx.Context, bindingID)
+	params := map[string]interface{}{}
+	if bv, ok := sb.(crossplane.BindValidater); ok {
+		params, err = bv.ValidateBindParams(rctx.Context, rawParameters)
+		if err != nil {
+			return res, apiresponses.NewFailureResponse(err, http.StatusBadRequest, "validate-bind-failed")
+		}
+	}
+
+	// The template and the namespace of the binding secret are checked before binding,
+	// so that an invalid template or a forbidden namespace don't create a binding.
+	tmpl, err := b.cp.CredentialsTemplate(rctx, p)
+	if err != nil {
+		return res, err
+	}
+	ns, err := b.cp.BindingSecretNamespace(rctx)
+	if err != nil {
+		return res, err
+	}
+
+	creds, err := sb.Bind(rctx.Context, bindingID, params)
+	if err != nil {
+		return res, err
+	}
+
+	creds, err = b.cp.RenderCredentials(rctx, tmpl, p, instance, bindingID, creds)
+	if err != nil {
+		return res, undoBind(rctx, sb, bindingID, err)
+	}
+	if ns != "" {
+		creds, err = b.cp.WriteBindingSecret(rctx, instance, bindingID, ns, creds)
+		if err != nil {
+			if derr := b.cp.DeleteBindingSecret(rctx, instance, bindingID); derr != nil {
+				rctx.Logger.Error("delete-binding-secret", derr)
+			}
+			return res, undoBind(rctx, sb, bindingID, err)
+		}
+	}
+	b.cp.RecordBindingEvent(rctx, instance, bindingID, true)
+
+	extras, err := b.bindingExtras(rctx, sb, instance, bindingID)
 	if err != nil {
 		return res, err
 	}
 
 	res.Credentials = creds
+	res.SyslogDrainURL = extras.SyslogDrainURL
+	res.RouteServiceURL = extras.RouteServiceURL
+	res.VolumeMounts = extras.VolumeMounts
 
 	return res, nil
 }
 
+// undoBind removes a binding whose credentials couldn't be returned, as the platform doesn't know about it.
+// It returns err, the reason the binding failed.
+func undoBind(rctx *reqcontext.ReqContext, sb crossplane.ServiceBinder, bindingID string, err error) error {
+	if uerr := crossplane.RollbackBind(rctx.Context, sb, bindingID); uerr != nil {
+		rctx.Logger.Error("undo-bind", uerr)
+	}
+	return err
+}
+
 // Unbind removes a binding.
 func (b Broker) Unbind(rctx *reqcontext.ReqContext, instanceID, bindingID, planID string) (domain.UnbindSpec, error) {
 	res := domain.UnbindSpec{
@@ -187,6 +291,12 @@ func (b Broker) Unbind(rctx *reqcontext.ReqContext, instanceID, bindingID, planI
 		return res, err
 	}
 
+	// The Secret is deleted before the binding, so that the platform retries unbinding if deleting it fails.
+	// It's found by the namespace recorded on the instance, so it's also deleted after disabling binding secrets.
+	if err := b.cp.DeleteBindingSecret(rctx, instance, bindingID); err != nil {
+		return res, err
+	}
+
 	err = sb.Unbind(rctx.Context, bindingID)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
@@ -194,6 +304,7 @@ func (b Broker) Unbind(rctx *reqcontext.ReqContext, instanceID, bindingID, planI
 		}
 		return res, err
 	}
+	b.cp.RecordBindingEvent(rctx, instance, bindingID, false)
 	return res, nil
 }
 
@@ -267,7 +378,7 @@ func (b Broker) LastBindingOperation(rctx *reqcontext.ReqContext, instanceID, pl
 func (b Broker) GetBinding(rctx *reqcontext.ReqContext, instanceID, bindingID string, details domain.FetchBindingDetails) (domain.GetBindingSpec, error) {
 	res := domain.GetBindingSpec{}
 
-	_, instance, err := b.getPlanInstance(rctx, details.PlanID, instanceID)
+	p, instance, err := b.getPlanInstance(rctx, details.PlanID, instanceID)
 	if err != nil {
 		return res, err
 	}
@@ -284,12 +395,70 @@ func (b Broker) GetBinding(rctx *reqcontext.ReqContext, instanceID, bindingID st
 	if err != nil {
 		return res, err
 	}
+	ref, err := b.cp.BindingSecretReference(rctx, instance, bindingID)
+	if err != nil {
+		return res, err
+	}
+	if ref != nil {
+		creds = ref
+	} else {
+		tmpl, err := b.cp.CredentialsTemplate(rctx, p)
+		if err != nil {
+			return res, err
+		}
+		creds, err = b.cp.RenderCredentials(rctx, tmpl, p, instance, bindingID, creds)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	extras, err := b.bindingExtras(rctx, sb, instance, bindingID)
+	if err != nil {
+		return res, err
+	}
 
 	res.Credentials = creds
+	res.SyslogDrainURL = extras.SyslogDrainURL
+	res.RouteServiceURL = extras.RouteServiceURL
+	res.VolumeMounts = extras.VolumeMounts
 
 	return res, nil
 }
 
+// bindingExtras returns the extras of a binding the service requires permission for in the catalog,
+// as platforms refuse bindings with extras they didn't grant permission for.
+func (b Broker) bindingExtras(rctx *reqcontext.ReqContext, sb crossplane.ServiceBinder, instance *crossplane.Instance, bindingID string) (crossplane.BindingExtras, error) {
+	extras := crossplane.BindingExtras{}
+	be, ok := sb.(crossplane.BindingExtender)
+	if !ok {
+		return extras, nil
+	}
+	xrd, err := b.cp.ServiceXRD(rctx, instance.Labels.ServiceID)
+	if err != nil || xrd == nil {
+		return extras, err
+	}
+	requires := requiredPermissions(xrd, rctx.Logger)
+	if len(requires) == 0 {
+		return extras, nil
+	}
+
+	all, err := be.BindingExtras(rctx.Context, bindingID)
+	if err != nil {
+		return extras, err
+	}
+	for _, p := range requires {
+		switch p {
+		case domain.PermissionSyslogDrain:
+			extras.SyslogDrainURL = all.SyslogDrainURL
+		case domain.PermissionRouteForwarding:
+			extras.RouteServiceURL = all.RouteServiceURL
+		case domain.PermissionVolumeMount:
+			extras.VolumeMounts = all.VolumeMounts
+		}
+	}
+	return extras, nil
+}
+
 // GetInstance gets a provisioned instance.
 func (b Broker) GetInstance(rctx *reqcontext.ReqContext, instanceID string, details domain.FetchInstanceDetails) (domain.GetInstanceDetailsSpec, error) {
 	res := domain.GetInstanceDetailsSpec{}
@@ -298,6 +467,10 @@ func (b Broker) GetInstance(rctx *reqcontext.ReqContext, instanceID string, deta
 	if err != nil {
 		return res, err
 	}
+	// Fetching an instance which is still being provisioned is not allowed by the OSB API.
+	if instance.Provisioning() {
+		return res, apiresponses.ErrConcurrentInstanceAccess
+	}
 
 	res.PlanID = p.Composition.GetName()
 	res.ServiceID = p.Labels.ServiceID
@@ -307,9 +480,42 @@ func (b Broker) GetInstance(rctx *reqcontext.ReqContext, instanceID string, deta
 		res.Parameters = params
 	}
 
+	res.DashboardURL = b.dashboardURL(rctx, p, instanceID, instance.GetClusterName())
+
+	res.Metadata = instanceMetadata(rctx, instance)
+
 	return res, nil
 }
 
+// instanceMetadata returns the labels the broker manages on the composite, the platform context and
+// the state of the Ready condition of the instance.
+func instanceMetadata(rctx *reqcontext.ReqContext, instance *crossplane.Instance) domain.InstanceMetadata {
+	m := domain.InstanceMetadata{
+		Labels:     map[string]string{},
+		Attributes: map[string]string{},
+	}
+	for k, v := range instance.Composite.GetLabels() {
+		if strings.HasPrefix(k, crossplane.SynToolsBase+"/") {
+			m.Labels[k] = v
+		}
+	}
+
+	pc, err := instance.PlatformContext()
+	if err != nil {
+		rctx.Logger.Error("invalid-platform-context", err, lager.Data{"instance-id": instance.ID()})
+	}
+	for k, v := range pc.Attributes() {
+		m.Attributes[k] = v
+	}
+
+	condition := instance.Composite.GetCondition(xrv1.TypeReady)
+	m.Attributes["status"] = string(condition.Reason)
+	if condition.Message != "" {
+		m.Attributes["status_message"] = condition.Message
+	}
+	return m
+}
+
 // Update allows to change the SLA level from standard -> premium (and vice-versa).
 func (b Broker) Update(rctx *reqcontext.ReqContext, instanceID, serviceID, oldPlanID, newPlanID string, rawParameters json.RawMessage) (domain.UpdateServiceSpec, error) {
 	res := domain.UpdateServiceSpec{}
@@ -326,25 +532,31 @@ func (b Broker) Update(rctx *reqcontext.ReqContext, instanceID, serviceID, oldPl
 	if err != nil {
 		return res, err
 	}
+	planResolved(rctx, np)
+	xrd, err := b.cp.ServiceXRD(rctx, p.Labels.ServiceID)
+	if err != nil {
+		return res, err
+	}
+	if xrd != nil {
+		p.UpdateTargets = xrd.UpdateTargets(p)
+	}
 
-	if !b.planComparer.AllowUpdate(*p, *np) {
+	if d := b.planComparer.Load().Explain(*p, *np); !d.Allowed {
 		rctx.Logger.Info("Plan change not permitted", lager.Data{
 			"old-plan-id": p.Labels.PlanName,
+			"new-plan-id": np.Labels.PlanName,
+			"reasons":     d.Reasons,
 		})
 		return res, ErrPlanChangeNotPermitted
 	}
-
-	instance.Composite.SetCompositionReference(&corev1.ObjectReference{
-		Name: np.Composition.GetName(),
-	})
-	instanceLabels := instance.Composite.GetLabels()
-	for _, l := range []string{
-		crossplane.PlanNameLabel,
-		crossplane.SLALabel,
-	} {
-		instanceLabels[l] = np.Composition.Labels[l]
+	if p.Composition.GetName() != np.Composition.GetName() {
+		if err := b.cp.CheckClusterCapacity(rctx, np, instance); err != nil {
+			return res, err
+		}
+		if err := b.checkDownsize(rctx, instance, np); err != nil {
+			return res, err
+		}
 	}
-	instance.Composite.SetLabels(instanceLabels)
 
 	ap := map[string]any{}
 	if len(rawParameters) != 0 {
@@ -377,6 +589,18 @@ func (b Broker) validateParams(rctx *reqcontext.ReqContext, instance *crossplane
 	return ap, nil
 }
 
+// checkDownsize lets the service implementation check whether the instance fits into the new plan.
+func (b Broker) checkDownsize(rctx *reqcontext.ReqContext, instance *crossplane.Instance, plan *crossplane.Plan) error {
+	sb, err := crossplane.ServiceBinderFactory(b.cp, instance.Labels.ServiceName, instance, rctx.Logger)
+	if err != nil {
+		return err
+	}
+	if dc, ok := sb.(crossplane.DownsizeChecker); ok {
+		return dc.CheckDownsize(rctx.Context, plan)
+	}
+	return nil
+}
+
 func (b Broker) getPlanInstance(rctx *reqcontext.ReqContext, planID, instanceID string) (*crossplane.Plan, *crossplane.Instance, error) {
 	if planID == "" {
 		rctx.Logger.Info("find-instance-without-plan", lager.Data{"instance-id": instanceID})
@@ -388,12 +612,14 @@ func (b Broker) getPlanInstance(rctx *reqcontext.ReqContext, planID, instanceID
 		if !exists {
 			return nil, nil, apiresponses.ErrInstanceDoesNotExist
 		}
+		planResolved(rctx, p)
 		return p, instance, nil
 	}
 	p, err := b.cp.Plan(rctx, planID)
 	if err != nil {
 		return nil, nil, err
 	}
+	planResolved(rctx, p)
 
 	instance, exists, err := b.cp.Instance(rctx, instanceID, p)
 	if err != nil {
@@ -2,6 +2,7 @@ package brokerapi
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -11,33 +12,46 @@ import (
 	"github.com/pivotal-cf/brokerapi/v8/domain/apiresponses"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 
+	"github.com/vshn/crossplane-service-broker/pkg/audit"
+	"github.com/vshn/crossplane-service-broker/pkg/config"
 	"github.com/vshn/crossplane-service-broker/pkg/crossplane"
 	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
 )
 
 // BrokerAPI implements a ServiceBroker.
 type BrokerAPI struct {
-	broker *Broker
-	logger lager.Logger
+	broker   *Broker
+	logger   lager.Logger
+	auditLog *audit.Log
 }
 
-// New sets up a new broker api
-func New(cp *crossplane.Crossplane, logger lager.Logger, pc crossplane.PlanUpdateChecker) *BrokerAPI {
+// New sets up a new broker api.
+// State-changing operations are recorded to auditLog, unless it is nil.
+func New(cp *crossplane.Crossplane, logger lager.Logger, pc crossplane.PlanUpdateChecker, auditLog *audit.Log) *BrokerAPI {
 	return &BrokerAPI{
-		broker: NewBroker(cp, pc),
-		logger: logger,
+		broker:   NewBroker(cp, pc),
+		logger:   logger,
+		auditLog: auditLog,
 	}
 }
 
+// Reload applies the reloadable settings of cfg and the plan update rules pc to the running broker.
+// Requests in flight are not affected.
+func (b BrokerAPI) Reload(cfg *config.Config, pc crossplane.PlanUpdateChecker) {
+	b.broker.cp.Reload(cfg)
+	b.broker.SetPlanUpdateChecker(pc)
+}
+
 // Services gets the catalog of services offered by the service broker
 //
 //	GET /v2/catalog
 func (b BrokerAPI) Services(ctx context.Context) ([]domain.Service, error) {
 	rctx := reqcontext.NewReqContext(ctx, b.logger, nil)
+	timer := newOperationTimer(rctx, "catalog")
 	rctx.Logger.Info("get-catalog")
 
 	res, err := b.broker.Services(rctx)
-	return res, APIResponseError(rctx, err)
+	return res, timer.observe(APIResponseError(rctx, err))
 }
 
 // Provision creates a new service instance
@@ -50,13 +64,26 @@ func (b BrokerAPI) Provision(ctx context.Context, instanceID string, details dom
 		"service-id":  details.ServiceID,
 	})
 	rctx.Logger.Info("provision-instance")
+	timer := newOperationTimer(rctx, "provision")
 
-	if !asyncAllowed {
-		return domain.ProvisionedServiceSpec{}, APIResponseError(rctx, apiresponses.ErrAsyncRequired)
+	var res domain.ProvisionedServiceSpec
+	err := setPlatform(rctx, details.RawContext)
+	switch {
+	case err != nil:
+	case !asyncAllowed:
+		err = apiresponses.ErrAsyncRequired
+	default:
+		res, err = b.broker.Provision(rctx, instanceID, details.PlanID, details.RawParameters)
 	}
-
-	res, err := b.broker.Provision(rctx, instanceID, details.PlanID, details.RawParameters)
-	return res, APIResponseError(rctx, err)
+	err = APIResponseError(rctx, err)
+	b.audit(rctx, audit.Event{
+		Operation:  audit.OperationProvision,
+		InstanceID: instanceID,
+		ServiceID:  details.ServiceID,
+		PlanID:     details.PlanID,
+		Parameters: details.RawParameters,
+	}, err)
+	return res, timer.observe(err)
 }
 
 // Deprovision deletes an existing service instance
@@ -69,9 +96,17 @@ func (b BrokerAPI) Deprovision(ctx context.Context, instanceID string, details d
 		"service-id":  details.ServiceID,
 	})
 	rctx.Logger.Info("deprovision-instance")
+	timer := newOperationTimer(rctx, "deprovision")
 
 	res, err := b.broker.Deprovision(rctx, instanceID, details.PlanID)
-	return res, APIResponseError(rctx, err)
+	err = APIResponseError(rctx, err)
+	b.audit(rctx, audit.Event{
+		Operation:  audit.OperationDeprovision,
+		InstanceID: instanceID,
+		ServiceID:  details.ServiceID,
+		PlanID:     details.PlanID,
+	}, err)
+	return res, timer.observe(err)
 }
 
 // GetInstance fetches information about a service instance
@@ -82,9 +117,10 @@ func (b BrokerAPI) GetInstance(ctx context.Context, instanceID string, details d
 		"instance-id": instanceID,
 	})
 	rctx.Logger.Info("get-instance")
+	timer := newOperationTimer(rctx, "get-instance")
 
 	res, err := b.broker.GetInstance(rctx, instanceID, details)
-	return res, APIResponseError(rctx, err)
+	return res, timer.observe(APIResponseError(rctx, err))
 }
 
 // Update modifies an existing service instance
@@ -97,17 +133,27 @@ func (b BrokerAPI) Update(ctx context.Context, instanceID string, details domain
 		"service-id":  details.ServiceID,
 	})
 	rctx.Logger.Info("update-service-instance")
+	timer := newOperationTimer(rctx, "update")
 
-	res, err := b.broker.Update(rctx, instanceID, details.ServiceID, details.PreviousValues.PlanID, details.PlanID, details.RawParameters)
-	if err != nil {
-		switch err {
-		case ErrPlanChangeNotPermitted, ErrServiceUpdateNotPermitted:
-			err = apiresponses.NewFailureResponse(err, http.StatusUnprocessableEntity, "update-instance-failed")
-		}
-		return res, APIResponseError(rctx, err)
+	var res domain.UpdateServiceSpec
+	err := setPlatform(rctx, details.RawContext)
+	if err == nil {
+		res, err = b.broker.Update(rctx, instanceID, details.ServiceID, details.PreviousValues.PlanID, details.PlanID, details.RawParameters)
 	}
-
-	return res, nil
+	switch err {
+	case ErrPlanChangeNotPermitted, ErrServiceUpdateNotPermitted:
+		err = apiresponses.NewFailureResponse(err, http.StatusUnprocessableEntity, "update-instance-failed")
+	}
+	err = APIResponseError(rctx, err)
+	b.audit(rctx, audit.Event{
+		Operation:    audit.OperationUpdate,
+		InstanceID:   instanceID,
+		ServiceID:    details.ServiceID,
+		PlanID:       details.PlanID,
+		PreviousPlan: details.PreviousValues.PlanID,
+		Parameters:   details.RawParameters,
+	}, err)
+	return res, timer.observe(err)
 }
 
 // LastOperation fetches last operation state for a service instance
@@ -120,9 +166,10 @@ func (b BrokerAPI) LastOperation(ctx context.Context, instanceID string, details
 		"service-id":  details.ServiceID,
 	})
 	rctx.Logger.Info("last-operation", lager.Data{"operation-data": details.OperationData})
+	timer := newOperationTimer(rctx, "last-operation")
 
 	res, err := b.broker.LastOperation(rctx, instanceID, details.PlanID)
-	return res, APIResponseError(rctx, err)
+	return res, timer.observe(APIResponseError(rctx, err))
 }
 
 // Bind creates a new service binding
@@ -136,9 +183,23 @@ func (b BrokerAPI) Bind(ctx context.Context, instanceID, bindingID string, detai
 		"service-id":  details.ServiceID,
 	})
 	rctx.Logger.Info("bind-instance")
+	timer := newOperationTimer(rctx, "bind")
 
-	res, err := b.broker.Bind(rctx, instanceID, bindingID, details.PlanID, asyncAllowed)
-	return res, APIResponseError(rctx, err)
+	var res domain.Binding
+	err := setPlatform(rctx, details.RawContext)
+	if err == nil {
+		res, err = b.broker.Bind(rctx, instanceID, bindingID, details.PlanID, details.RawParameters, asyncAllowed)
+	}
+	err = APIResponseError(rctx, err)
+	b.audit(rctx, audit.Event{
+		Operation:  audit.OperationBind,
+		InstanceID: instanceID,
+		BindingID:  bindingID,
+		ServiceID:  details.ServiceID,
+		PlanID:     details.PlanID,
+		Parameters: details.RawParameters,
+	}, err)
+	return res, timer.observe(err)
 }
 
 // Unbind deletes an existing service binding
@@ -152,9 +213,18 @@ func (b BrokerAPI) Unbind(ctx context.Context, instanceID, bindingID string, det
 		"service-id":  details.ServiceID,
 	})
 	rctx.Logger.Info("unbind-instance")
+	timer := newOperationTimer(rctx, "unbind")
 
 	res, err := b.broker.Unbind(rctx, instanceID, bindingID, details.PlanID)
-	return res, APIResponseError(rctx, err)
+	err = APIResponseError(rctx, err)
+	b.audit(rctx, audit.Event{
+		Operation:  audit.OperationUnbind,
+		InstanceID: instanceID,
+		BindingID:  bindingID,
+		ServiceID:  details.ServiceID,
+		PlanID:     details.PlanID,
+	}, err)
+	return res, timer.observe(err)
 }
 
 // GetBinding fetches an existing service binding
@@ -168,9 +238,10 @@ func (b BrokerAPI) GetBinding(ctx context.Context, instanceID, bindingID string,
 		"binding-id":  bindingID,
 	})
 	rctx.Logger.Info("get-binding")
+	timer := newOperationTimer(rctx, "get-binding")
 
 	res, err := b.broker.GetBinding(rctx, instanceID, bindingID, details)
-	return res, APIResponseError(rctx, err)
+	return res, timer.observe(APIResponseError(rctx, err))
 }
 
 // LastBindingOperation fetches last operation state for a service binding
@@ -184,9 +255,20 @@ func (b BrokerAPI) LastBindingOperation(ctx context.Context, instanceID, binding
 		"service-id":  details.ServiceID,
 	})
 	rctx.Logger.Info("last-binding-operation")
+	timer := newOperationTimer(rctx, "last-binding-operation")
 
 	res, err := b.broker.LastBindingOperation(rctx, instanceID, details.PlanID, bindingID)
-	return res, APIResponseError(rctx, err)
+	return res, timer.observe(APIResponseError(rctx, err))
+}
+
+// setPlatform parses the platform context of the request into rctx.
+func setPlatform(rctx *reqcontext.ReqContext, raw json.RawMessage) error {
+	if err := rctx.SetPlatform(raw); err != nil {
+		return apiresponses.NewFailureResponseBuilder(err, http.StatusBadRequest, "invalid-context").
+			WithErrorKey("InvalidContext").
+			Build()
+	}
+	return nil
 }
 
 // APIResponseError converts an error to a proper API error
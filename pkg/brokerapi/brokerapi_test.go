@@ -102,6 +102,7 @@ func (ts *EnvTestSuite) TestBrokerAPI_Services() {
 							Metadata: &domain.ServicePlanMetadata{
 								DisplayName: "small",
 							},
+							PlanUpdatable: integration.BoolPtr(false),
 						},
 						{
 							ID:          "1-2",
@@ -112,6 +113,7 @@ func (ts *EnvTestSuite) TestBrokerAPI_Services() {
 							Metadata: &domain.ServicePlanMetadata{
 								DisplayName: "small",
 							},
+							PlanUpdatable: integration.BoolPtr(false),
 						},
 					},
 					Metadata: &domain.ServiceMetadata{
@@ -123,7 +125,7 @@ func (ts *EnvTestSuite) TestBrokerAPI_Services() {
 		},
 	}
 
-	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer)
+	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer, nil)
 
 	for _, tt := range tests {
 		ts.Run(tt.name, func() {
@@ -208,6 +210,28 @@ func (ts *EnvTestSuite) TestBrokerAPI_Provision() {
 			want:    &domain.ProvisionedServiceSpec{IsAsync: true},
 			wantErr: nil,
 		},
+		{
+			name: "returns the dashboard URL of the service",
+			args: args{
+				ctx:        ts.givenContext(),
+				instanceID: "1",
+				details: domain.ProvisionDetails{
+					PlanID:    "1-1",
+					ServiceID: "1",
+				},
+				asyncAllowed: true,
+			},
+			resources: func() []client.Object {
+				service := integration.NewTestService("1", crossplane.RedisService)
+				service.Annotations[crossplane.DashboardURLAnnotation] = "https://dashboard.example.com/{{ .ServiceID }}/{{ .InstanceID }}"
+				return []client.Object{
+					service,
+					integration.NewTestServicePlan("1", "1-1", crossplane.RedisService).Composition,
+				}
+			},
+			want:    &domain.ProvisionedServiceSpec{IsAsync: true, DashboardURL: "https://dashboard.example.com/1/1"},
+			wantErr: nil,
+		},
 		{
 			name: "returns already exists if instance already exists",
 			args: args{
@@ -300,7 +324,7 @@ func (ts *EnvTestSuite) TestBrokerAPI_Provision() {
 		},
 	}
 
-	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer)
+	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer, nil)
 
 	for _, tt := range tests {
 		ts.Run(tt.name, func() {
@@ -417,7 +441,7 @@ func (ts *EnvTestSuite) TestBrokerAPI_Deprovision() {
 		},
 	}
 
-	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer)
+	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer, nil)
 
 	for _, tt := range tests {
 		ts.Run(tt.name, func() {
@@ -575,7 +599,7 @@ func (ts *EnvTestSuite) TestBrokerAPI_LastOperation() {
 			wantErr: nil,
 		},
 	}
-	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer)
+	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer, nil)
 
 	for _, tt := range tests {
 		ts.Run(tt.name, func() {
@@ -789,7 +813,7 @@ func (ts *EnvTestSuite) TestBrokerAPI_LastBindingOperation() {
 			wantErr: apiresponses.ErrBindingDoesNotExist.AppendErrorMessage(`(correlation-id: "corrid")`),
 		},
 	}
-	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer)
+	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer, nil)
 
 	for _, tt := range tests {
 		ts.Run(tt.name, func() {
@@ -915,6 +939,73 @@ func (ts *EnvTestSuite) TestBrokerAPI_Bind() {
 			wantComparisonFunc: assert.Equal,
 			wantErr:            nil,
 		},
+		{
+			name: "refuses to bind with a credentials template which can't be parsed",
+			args: args{
+				ctx:        ctx,
+				instanceID: "1-1-1",
+				bindingID:  "1",
+				details: domain.BindDetails{
+					PlanID:    "1-1",
+					ServiceID: "1",
+				},
+			},
+			resources: func() (func(c client.Client) error, []client.Object) {
+				servicePlan := integration.NewTestServicePlan("1", "1-1", crossplane.RedisService)
+				servicePlan.Composition.Annotations[crossplane.CredentialsTemplateAnnotation] = `{"host": {{ json .Credentials.host }`
+				instance := integration.NewTestInstance("1-1-1", servicePlan, crossplane.RedisService, "", "")
+				objs := []client.Object{
+					integration.NewTestService("1", crossplane.RedisService),
+					servicePlan.Composition,
+					instance,
+				}
+				return func(c client.Client) error {
+					return integration.UpdateInstanceConditions(ctx, c, servicePlan, instance, xrv1.TypeReady, corev1.ConditionTrue, xrv1.ReasonAvailable)
+				}, objs
+			},
+			want:    nil,
+			wantErr: errors.New(`annotation "service.syn.tools/credentials-template" is not a valid template: template: credentials:1: unexpected "}" in operand (correlation-id: "corrid")`),
+		},
+		{
+			name: "returns the syslog drain of a service requiring it",
+			args: args{
+				ctx:        ctx,
+				instanceID: "1-1-1",
+				bindingID:  "1",
+				details: domain.BindDetails{
+					PlanID:    "1-1",
+					ServiceID: "1",
+				},
+			},
+			resources: func() (func(c client.Client) error, []client.Object) {
+				service := integration.NewTestService("1", crossplane.RedisService)
+				service.Annotations[crossplane.RequiresAnnotation] = `["syslog_drain"]`
+				servicePlan := integration.NewTestServicePlan("1", "1-1", crossplane.RedisService)
+				instance := integration.NewTestInstance("1-1-1", servicePlan, crossplane.RedisService, "", "")
+				objs := []client.Object{
+					service,
+					servicePlan.Composition,
+					instance,
+					integration.NewTestSecret(integration.TestNamespace, "1-1-1", map[string]string{
+						xrv1.ResourceCredentialsSecretPortKey:     "1234",
+						xrv1.ResourceCredentialsSecretEndpointKey: "localhost",
+						xrv1.ResourceCredentialsSecretPasswordKey: "supersecret",
+						"sentinelPort":               "21234",
+						crossplane.SyslogDrainURLKey: "syslog-tls://logs.example.com:6514",
+					}),
+				}
+				return func(c client.Client) error {
+					return integration.UpdateInstanceConditions(ctx, c, servicePlan, instance, xrv1.TypeReady, corev1.ConditionTrue, xrv1.ReasonAvailable)
+				}, objs
+			},
+			want: &domain.Binding{
+				SyslogDrainURL: "syslog-tls://logs.example.com:6514",
+			},
+			wantComparisonFunc: func(t assert.TestingT, want, got interface{}, _ ...interface{}) bool {
+				return assert.Equal(t, want.(domain.Binding).SyslogDrainURL, got.(domain.Binding).SyslogDrainURL)
+			},
+			wantErr: nil,
+		},
 		{
 			name: "creates a redis instance and binds it asynchronously",
 			args: args{
@@ -1161,7 +1252,7 @@ func (ts *EnvTestSuite) TestBrokerAPI_Bind() {
 		},
 	}
 
-	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer)
+	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer, nil)
 
 	for _, tt := range tests {
 		ts.Run(tt.name, func() {
@@ -1312,7 +1403,7 @@ func (ts *EnvTestSuite) TestBrokerAPI_GetBinding() {
 		},
 	}
 
-	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer)
+	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer, nil)
 
 	for _, tt := range tests {
 		ts.Run(tt.name, func() {
@@ -1372,20 +1463,100 @@ func (ts *EnvTestSuite) TestBrokerAPI_GetInstance() {
 				servicePlan := integration.NewTestServicePlan("1", "1-1", crossplane.RedisService)
 				instance := integration.NewTestInstance("1-1-1", servicePlan, crossplane.RedisService, "", "")
 
-				return nil, []client.Object{
+				objs := []client.Object{
 					integration.NewTestService("1", crossplane.RedisService),
 					integration.NewTestServicePlan("1", "1-2", crossplane.RedisService).Composition,
 					servicePlan.Composition,
 					instance,
 				}
+				return func(c client.Client) error {
+					return integration.UpdateInstanceConditions(ctx, c, servicePlan, instance, xrv1.TypeReady, corev1.ConditionTrue, xrv1.ReasonAvailable)
+				}, objs
 			},
 			want: &domain.GetInstanceDetailsSpec{
 				PlanID:     "1-1",
 				ServiceID:  "1",
 				Parameters: nil,
+				Metadata: domain.InstanceMetadata{
+					Labels: map[string]string{
+						crossplane.PlanNameLabel:    "small1-1",
+						crossplane.ServiceIDLabel:   "",
+						crossplane.SLALabel:         "standard",
+						crossplane.ServiceNameLabel: string(crossplane.RedisService),
+						crossplane.ClusterLabel:     "dbaas-test-cluster",
+					},
+					Attributes: map[string]string{"status": string(xrv1.ReasonAvailable)},
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "gets an instance with dashboard URL and platform context",
+			args: args{
+				ctx:        ctx,
+				instanceID: "1-1-1",
+				bindingID:  "1",
+				planID:     "1-1",
+			},
+			resources: func() (func(c client.Client) error, []client.Object) {
+				servicePlan := integration.NewTestServicePlan("1", "1-1", crossplane.RedisService)
+				servicePlan.Composition.Annotations[crossplane.DashboardURLAnnotation] = "https://{{ .Cluster }}.example.com/d/{{ .InstanceID }}"
+				instance := integration.NewTestInstance("1-1-1", servicePlan, crossplane.RedisService, "", "")
+				instance.SetAnnotations(map[string]string{crossplane.PlatformContextAnnotation: `{"platform":"kubernetes","namespace":"team-a"}`})
+
+				objs := []client.Object{
+					integration.NewTestService("1", crossplane.RedisService),
+					servicePlan.Composition,
+					instance,
+				}
+				return func(c client.Client) error {
+					return integration.UpdateInstanceConditions(ctx, c, servicePlan, instance, xrv1.TypeReady, corev1.ConditionFalse, xrv1.ReasonUnavailable)
+				}, objs
+			},
+			want: &domain.GetInstanceDetailsSpec{
+				PlanID:       "1-1",
+				ServiceID:    "1",
+				DashboardURL: "https://dbaas-test-cluster.example.com/d/1-1-1",
+				Metadata: domain.InstanceMetadata{
+					Labels: map[string]string{
+						crossplane.PlanNameLabel:    "small1-1",
+						crossplane.ServiceIDLabel:   "",
+						crossplane.SLALabel:         "standard",
+						crossplane.ServiceNameLabel: string(crossplane.RedisService),
+						crossplane.ClusterLabel:     "dbaas-test-cluster",
+					},
+					Attributes: map[string]string{
+						"platform":  "kubernetes",
+						"namespace": "team-a",
+						"status":    string(xrv1.ReasonUnavailable),
+					},
+				},
 			},
 			wantErr: nil,
 		},
+		{
+			name: "refuses to get an instance which is still provisioning",
+			args: args{
+				ctx:        ctx,
+				instanceID: "1-1-1",
+				bindingID:  "1",
+				planID:     "1-1",
+			},
+			resources: func() (func(c client.Client) error, []client.Object) {
+				servicePlan := integration.NewTestServicePlan("1", "1-1", crossplane.RedisService)
+				instance := integration.NewTestInstance("1-1-1", servicePlan, crossplane.RedisService, "", "")
+
+				objs := []client.Object{
+					integration.NewTestService("1", crossplane.RedisService),
+					servicePlan.Composition,
+					instance,
+				}
+				return func(c client.Client) error {
+					return integration.UpdateInstanceConditions(ctx, c, servicePlan, instance, xrv1.TypeReady, corev1.ConditionFalse, xrv1.ReasonCreating)
+				}, objs
+			},
+			wantErr: errors.New(`instance is being updated and cannot be retrieved (correlation-id: "corrid")`),
+		},
 
 		{
 			name: "gets an instance with parameters",
@@ -1399,12 +1570,15 @@ func (ts *EnvTestSuite) TestBrokerAPI_GetInstance() {
 				servicePlan := integration.NewTestServicePlan("1", "1-1", crossplane.MariaDBDatabaseService)
 				instance := integration.NewTestInstance("1-1-1", servicePlan, crossplane.MariaDBDatabaseService, "", "1")
 
-				return nil, []client.Object{
+				objs := []client.Object{
 					integration.NewTestService("1", crossplane.MariaDBDatabaseService),
 					integration.NewTestServicePlan("1", "1-2", crossplane.MariaDBDatabaseService).Composition,
 					servicePlan.Composition,
 					instance,
 				}
+				return func(c client.Client) error {
+					return integration.UpdateInstanceConditions(ctx, c, servicePlan, instance, xrv1.TypeReady, corev1.ConditionTrue, xrv1.ReasonAvailable)
+				}, objs
 			},
 			want: &domain.GetInstanceDetailsSpec{
 				PlanID:    "1-1",
@@ -1412,12 +1586,23 @@ func (ts *EnvTestSuite) TestBrokerAPI_GetInstance() {
 				Parameters: map[string]interface{}{
 					"parent_reference": "1",
 				},
+				Metadata: domain.InstanceMetadata{
+					Labels: map[string]string{
+						crossplane.PlanNameLabel:    "small1-1",
+						crossplane.ServiceIDLabel:   "",
+						crossplane.SLALabel:         "standard",
+						crossplane.ServiceNameLabel: string(crossplane.MariaDBDatabaseService),
+						crossplane.ClusterLabel:     "dbaas-test-cluster",
+						crossplane.ParentIDLabel:    "1",
+					},
+					Attributes: map[string]string{"status": string(xrv1.ReasonAvailable)},
+				},
 			},
 			wantErr: nil,
 		},
 	}
 
-	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer)
+	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer, nil)
 
 	for _, tt := range tests {
 		ts.Run(tt.name, func() {
@@ -1634,7 +1819,7 @@ func (ts *EnvTestSuite) TestBrokerAPI_Update() {
 		},
 	}
 
-	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer)
+	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer, nil)
 
 	for _, tt := range tests {
 		ts.Run(tt.name, func() {
@@ -1776,7 +1961,7 @@ func (ts *EnvTestSuite) TestBrokerAPI_Unbind() {
 		},
 	}
 
-	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer)
+	bAPI := New(ts.Crossplane, ts.Logger, ts.PlanComparer, nil)
 
 	for _, tt := range tests {
 		ts.Run(tt.name, func() {
@@ -10,7 +10,7 @@ import (
 	"github.com/vshn/crossplane-service-broker/pkg/crossplane"
 )
 
-func newService(service *crossplane.ServiceXRD, plans []domain.ServicePlan, logger lager.Logger) domain.Service {
+func newService(service *crossplane.ServiceXRD, plans []domain.ServicePlan, client *crossplane.DashboardClient, logger lager.Logger) domain.Service {
 	meta := &domain.ServiceMetadata{}
 	if err := json.Unmarshal([]byte(service.Metadata), meta); err != nil {
 		logger.Error("parse-metadata", err)
@@ -22,6 +22,15 @@ func newService(service *crossplane.ServiceXRD, plans []domain.ServicePlan, logg
 		logger.Error("parse-tags", err, lager.Data{"service": service.XRD.Name})
 	}
 
+	var dashboardClient *domain.ServiceDashboardClient
+	if client != nil {
+		dashboardClient = &domain.ServiceDashboardClient{
+			ID:          client.ID,
+			Secret:      client.Secret,
+			RedirectURI: client.RedirectURI,
+		}
+	}
+
 	return domain.Service{
 		ID:                   service.Labels.ServiceID,
 		Name:                 string(service.Labels.ServiceName),
@@ -33,7 +42,22 @@ func newService(service *crossplane.ServiceXRD, plans []domain.ServicePlan, logg
 		Plans:                plans,
 		Metadata:             meta,
 		Tags:                 tags,
+		DashboardClient:      dashboardClient,
+		Requires:             requiredPermissions(service, logger),
+	}
+}
+
+// requiredPermissions parses the permissions the service requires. Invalid lists are logged and ignored.
+func requiredPermissions(service *crossplane.ServiceXRD, logger lager.Logger) []domain.RequiredPermission {
+	if service.Requires == "" {
+		return nil
+	}
+	var requires []domain.RequiredPermission
+	if err := json.Unmarshal([]byte(service.Requires), &requires); err != nil {
+		logger.Error("parse-requires", err, lager.Data{"service": service.XRD.Name})
+		return nil
 	}
+	return requires
 }
 
 func newServicePlan(plan *crossplane.Plan, logger lager.Logger) domain.ServicePlan {
@@ -0,0 +1,101 @@
+package brokerapi
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/pivotal-cf/brokerapi/v8/domain/apiresponses"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/vshn/crossplane-service-broker/pkg/crossplane"
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+)
+
+// codeSuccess is used as code label for successful operations, as the exact status code is determined by brokerapi.
+const codeSuccess = "2xx"
+
+// labelUnknown is used as service and plan label as long as the plan of an operation isn't resolved from the catalog.
+// The IDs sent by the platform aren't used, so that arbitrary IDs can't create new time series.
+const labelUnknown = "unknown"
+
+var (
+	operationLabels = []string{"operation", "service_id", "plan_id", "code", "error"}
+
+	operationsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "osb_broker_operations_total",
+		Help: "The total number of processed service broker operations by operation, service, plan and outcome.",
+	}, operationLabels)
+	operationsDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "osb_broker_operation_duration_seconds",
+		Help:    "The duration of service broker operations by operation, service, plan and outcome.",
+		Buckets: prometheus.DefBuckets,
+	}, operationLabels)
+)
+
+// operationTimer measures the duration and the outcome of a single service broker operation.
+type operationTimer struct {
+	operation string
+	serviceID string
+	planID    string
+	start     time.Time
+}
+
+type operationTimerKey struct{}
+
+// newOperationTimer starts measuring an operation. The timer is added to the context of rctx,
+// so that the broker can set the service and plan labels once it resolved the plan, see planResolved.
+func newOperationTimer(rctx *reqcontext.ReqContext, operation string) *operationTimer {
+	t := &operationTimer{
+		operation: operation,
+		serviceID: labelUnknown,
+		planID:    labelUnknown,
+		start:     time.Now(),
+	}
+	rctx.Context = context.WithValue(rctx.Context, operationTimerKey{}, t)
+	return t
+}
+
+// planResolved sets the service and plan labels of the operation measured in rctx to the ones of plan.
+func planResolved(rctx *reqcontext.ReqContext, plan *crossplane.Plan) {
+	t, ok := rctx.Context.Value(operationTimerKey{}).(*operationTimer)
+	if !ok {
+		return
+	}
+	t.serviceID = plan.Labels.ServiceID
+	t.planID = plan.Composition.Name
+}
+
+// observe records the operation with the outcome derived from err and returns err unchanged.
+func (t *operationTimer) observe(err error) error {
+	code, key := outcome(err)
+	labels := prometheus.Labels{
+		"operation":  t.operation,
+		"service_id": t.serviceID,
+		"plan_id":    t.planID,
+		"code":       code,
+		"error":      key,
+	}
+	operationsCounter.With(labels).Inc()
+	operationsDuration.With(labels).Observe(time.Since(t.start).Seconds())
+	return err
+}
+
+// outcome returns the status code and error key of the given error.
+func outcome(err error) (string, string) {
+	if err == nil {
+		return codeSuccess, ""
+	}
+	var apiErr *apiresponses.FailureResponse
+	if !errors.As(err, &apiErr) {
+		return "500", "internal-server-error"
+	}
+
+	key := apiErr.LoggerAction()
+	if er, ok := apiErr.ErrorResponse().(apiresponses.ErrorResponse); ok && er.Error != "" {
+		key = er.Error
+	}
+	return strconv.Itoa(apiErr.ValidatedStatusCode(nil)), key
+}
@@ -0,0 +1,71 @@
+package brokerapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	xv1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/pivotal-cf/brokerapi/v8/domain/apiresponses"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/vshn/crossplane-service-broker/pkg/crossplane"
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+)
+
+func Test_outcome(t *testing.T) {
+	tests := map[string]struct {
+		err      error
+		wantCode string
+		wantKey  string
+	}{
+		"success": {
+			wantCode: codeSuccess,
+		},
+		"plain error": {
+			err:      errors.New("boom"),
+			wantCode: "500",
+			wantKey:  "internal-server-error",
+		},
+		"failure response with error key": {
+			err:      apiresponses.ErrConcurrentInstanceAccess,
+			wantCode: "422",
+			wantKey:  "ConcurrencyError",
+		},
+		"failure response without error key": {
+			err:      apiresponses.NewFailureResponse(errors.New("boom"), http.StatusBadRequest, "validate-update-failed"),
+			wantCode: "400",
+			wantKey:  "validate-update-failed",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			code, key := outcome(tc.err)
+			assert.Equal(t, tc.wantCode, code)
+			assert.Equal(t, tc.wantKey, key)
+		})
+	}
+}
+
+func Test_operationTimer_Labels(t *testing.T) {
+	plan := &crossplane.Plan{
+		Composition: &xv1.Composition{ObjectMeta: metav1.ObjectMeta{Name: "redis-small"}},
+		Labels:      &crossplane.Labels{ServiceID: "redis"},
+	}
+
+	rctx := reqcontext.NewReqContext(context.Background(), lager.NewLogger("test"), nil)
+	timer := newOperationTimer(rctx, "test-unresolved")
+	_ = timer.observe(errors.New("boom"))
+	assert.Equal(t, 1.0, testutil.ToFloat64(operationsCounter.WithLabelValues("test-unresolved", labelUnknown, labelUnknown, "500", "internal-server-error")),
+		"the IDs of an unresolved plan must not be used as labels")
+
+	rctx = reqcontext.NewReqContext(context.Background(), lager.NewLogger("test"), nil)
+	timer = newOperationTimer(rctx, "test-resolved")
+	planResolved(rctx, plan)
+	_ = timer.observe(nil)
+	assert.Equal(t, 1.0, testutil.ToFloat64(operationsCounter.WithLabelValues("test-resolved", "redis", "redis-small", codeSuccess, "")))
+}
@@ -3,12 +3,14 @@ package config
 import (
 	"fmt"
 	"io"
+	"math"
 	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	"code.cloudfoundry.org/lager"
 	http "github.com/hashicorp/go-cleanhttp"
 	"github.com/pascaldekloe/jwt"
 )
@@ -28,13 +30,55 @@ type Config struct {
 	MaxHeaderBytes     int
 	PlanUpdateSizeRule string
 	PlanUpdateSLARule  string
-	EnableMetrics      bool
-	MetricsDomain      string
+	PlanUpdateRules    string
+	// PlanUpdateInlineRules are structured plan update rules in the format of the rules file.
+	PlanUpdateInlineRules string
+	EnableMetrics         bool
+	MetricsDomain         string
+	TLSCertFile           string
+	TLSKeyFile            string
+	TLSClientCAFile       string
+	RateLimit             float64
+	RateLimitBurst        int
+	QuotaPerService       int
+	QuotaPerPlan          int
+	TracingEndpoint       string
+	TracingSampleRatio    float64
+	LogLevel              lager.LogLevel
+	LogFormat             string
+	LogLevelOverrides     map[string]lager.LogLevel
+	AuditSink             string
+	BindingSecrets        bool
+	// BindingSecretsNamespaces are the namespaces binding secrets may be delivered to.
+	BindingSecretsNamespaces []string
 }
 
 // GetEnv is an interface that allows to get variables from the environment
 type GetEnv func(string) string
 
+// LookupEnv is like GetEnv, but also reports whether the variable is set, like os.LookupEnv.
+type LookupEnv func(string) (string, bool)
+
+// SettingError is returned if the setting Name has an invalid value.
+type SettingError struct {
+	// Name is the env variable of the setting.
+	Name string
+	// Err describes what's wrong with the setting, without mentioning its name.
+	Err error
+}
+
+func (e *SettingError) Error() string {
+	return e.Name + " " + e.Err.Error()
+}
+
+func (e *SettingError) Unwrap() error {
+	return e.Err
+}
+
+func settingErrorf(name, format string, a ...interface{}) error {
+	return &SettingError{Name: name, Err: fmt.Errorf(format, a...)}
+}
+
 type keyLoadingFun func(keys *jwt.KeyRegister, content []byte) (int, error)
 
 const (
@@ -81,6 +125,53 @@ const (
 	EnvPlanUpdateSLA = "OSB_PLAN_UPDATE_SLA_RULES"
 	// EnvPlanUpdateSize is a set of `|` seprated white-list rules for plan size changes
 	EnvPlanUpdateSize = "OSB_PLAN_UPDATE_SIZE_RULES"
+	// EnvPlanUpdateRules is the path to a YAML or JSON file of structured plan update rules.
+	// If set, EnvPlanUpdateSLA and EnvPlanUpdateSize are ignored.
+	EnvPlanUpdateRules = "OSB_PLAN_UPDATE_RULES_FILE"
+	// EnvPlanUpdateInlineRules are structured plan update rules as YAML or JSON, in the format of the rules file.
+	// If set, EnvPlanUpdateSLA and EnvPlanUpdateSize are ignored.
+	EnvPlanUpdateInlineRules = "OSB_PLAN_UPDATE_RULES"
+
+	// EnvTLSCertFile is the path to a PEM encoded certificate (chain). If set, the broker serves HTTPS.
+	EnvTLSCertFile = "OSB_TLS_CERT_FILE"
+	// EnvTLSKeyFile is the path to the PEM encoded private key belonging to EnvTLSCertFile.
+	EnvTLSKeyFile = "OSB_TLS_KEY_FILE"
+	// EnvTLSClientCAFile is the path to a PEM encoded CA bundle which is used to verify client certificates.
+	EnvTLSClientCAFile = "OSB_TLS_CLIENT_CA_FILE"
+
+	// EnvRateLimit sets the number of requests per second a single principal may send to the OSB API.
+	EnvRateLimit = "OSB_RATE_LIMIT"
+	// EnvRateLimitBurst sets the number of requests a single principal may send at once, exceeding EnvRateLimit.
+	EnvRateLimitBurst = "OSB_RATE_LIMIT_BURST"
+	// EnvQuotaPerService sets the maximum number of instances a single principal may provision per service.
+	EnvQuotaPerService = "OSB_QUOTA_INSTANCES_PER_SERVICE"
+	// EnvQuotaPerPlan sets the maximum number of instances a single principal may provision per plan.
+	EnvQuotaPerPlan = "OSB_QUOTA_INSTANCES_PER_PLAN"
+
+	// EnvTracingEndpoint sets the URL of an OTLP/HTTP endpoint (e.g. an OpenTelemetry collector) traces are exported to.
+	// Tracing is disabled if it is empty.
+	EnvTracingEndpoint = "OSB_TRACING_OTLP_ENDPOINT"
+	// EnvTracingSampleRatio sets the ratio of traces which are sampled, unless the calling platform already decided.
+	EnvTracingSampleRatio = "OSB_TRACING_SAMPLE_RATIO"
+
+	// EnvLogLevel sets the minimum level of log messages, one of `debug`, `info`, `error` or `fatal`.
+	EnvLogLevel = "OSB_LOG_LEVEL"
+	// EnvLogFormat sets the format of log messages, one of the LogFormat* constants.
+	EnvLogFormat = "OSB_LOG_FORMAT"
+	// EnvLogLevelOverrides is a comma-separated list of `component=level` pairs overriding EnvLogLevel per component.
+	EnvLogLevelOverrides = "OSB_LOG_LEVEL_OVERRIDES"
+
+	// EnvAuditSink defines where audit events of state-changing operations are written to.
+	// Supported are `file:///path/to/file`, `http(s)://` webhook URLs and AuditSinkKubernetesEvents.
+	// Audit events are disabled if it is empty.
+	EnvAuditSink = "OSB_AUDIT_SINK"
+
+	// EnvBindingSecrets defines if bindings of Kubernetes platforms are delivered as Secrets in the namespace of the
+	// platform context, instead of returning the credentials in the response.
+	EnvBindingSecrets = "OSB_BINDING_SECRETS_ENABLED"
+	// EnvBindingSecretsNamespaces is the comma-separated list of the namespaces binding secrets may be delivered to.
+	// It's required if EnvBindingSecrets is enabled. Bind requests of other namespaces are refused.
+	EnvBindingSecretsNamespaces = "OSB_BINDING_SECRETS_NAMESPACES"
 
 	// EnvEnableMetrics defines if metrics endpoints are returned.
 	EnvEnableMetrics = "ENABLE_METRICS"
@@ -93,21 +184,46 @@ const (
 	defaultUsernameClaim      = "sub"
 	defaultSLAUpdateRules     = "standard>premium|premium>standard"
 	defaultEnableMetrics      = false
+	defaultBindingSecrets     = false
+	defaultTracingSampleRatio = 1.0
+	defaultLogLevel           = lager.INFO
+	defaultLogFormat          = LogFormatPretty
+)
+
+// AuditSinkKubernetesEvents configures audit events to be recorded as Kubernetes Events on the composites.
+const AuditSinkKubernetesEvents = "kubernetes-events"
+
+const (
+	// LogFormatPretty logs lager JSON messages with human-readable timestamps and levels.
+	LogFormatPretty = "pretty"
+	// LogFormatJSON logs plain lager JSON messages.
+	LogFormatJSON = "json"
+	// LogFormatLogfmt logs messages as logfmt key-value pairs.
+	LogFormatLogfmt = "logfmt"
+	// LogFormatSlog logs messages in the JSON format of log/slog.
+	LogFormatSlog = "slog"
 )
 
 // ReadConfig reads env variables using the passed function.
 func ReadConfig(getEnv GetEnv) (*Config, error) {
 	cfg := Config{
-		Kubeconfig:         getEnv(EnvKubeconfig),
-		Username:           getEnv(EnvUsername),
-		Password:           getEnv(EnvPassword),
-		UsernameClaim:      getEnv(EnvUsernameClaim),
-		Namespace:          getEnv(EnvNamespace),
-		ListenAddr:         getEnv(EnvHTTPListenAddr),
-		JWKeyRegister:      &jwt.KeyRegister{},
-		PlanUpdateSizeRule: getEnv(EnvPlanUpdateSize),
-		PlanUpdateSLARule:  getEnv(EnvPlanUpdateSLA),
-		MetricsDomain:      getEnv(EnvMetricsDomain),
+		Kubeconfig:            getEnv(EnvKubeconfig),
+		Username:              getEnv(EnvUsername),
+		Password:              getEnv(EnvPassword),
+		UsernameClaim:         getEnv(EnvUsernameClaim),
+		Namespace:             getEnv(EnvNamespace),
+		ListenAddr:            getEnv(EnvHTTPListenAddr),
+		JWKeyRegister:         &jwt.KeyRegister{},
+		PlanUpdateSizeRule:    getEnv(EnvPlanUpdateSize),
+		PlanUpdateSLARule:     getEnv(EnvPlanUpdateSLA),
+		PlanUpdateRules:       getEnv(EnvPlanUpdateRules),
+		PlanUpdateInlineRules: getEnv(EnvPlanUpdateInlineRules),
+		MetricsDomain:         getEnv(EnvMetricsDomain),
+		TLSCertFile:           getEnv(EnvTLSCertFile),
+		TLSKeyFile:            getEnv(EnvTLSKeyFile),
+		TLSClientCAFile:       getEnv(EnvTLSClientCAFile),
+		TracingEndpoint:       getEnv(EnvTracingEndpoint),
+		AuditSink:             getEnv(EnvAuditSink),
 	}
 
 	if cfg.PlanUpdateSLARule == "" {
@@ -154,12 +270,65 @@ func ReadConfig(getEnv GetEnv) (*Config, error) {
 	}
 	cfg.EnableMetrics = enableMetrics
 
+	bindingSecrets, err := getBindingSecrets(getEnv)
+	if err != nil {
+		return nil, err
+	}
+	cfg.BindingSecrets = bindingSecrets
+	cfg.BindingSecretsNamespaces = splitList(getEnv(EnvBindingSecretsNamespaces))
+	if cfg.BindingSecrets && len(cfg.BindingSecretsNamespaces) == 0 {
+		return nil, settingErrorf(EnvBindingSecretsNamespaces, "is required, but was not defined or is empty")
+	}
+
 	metricsDomain, err := getMetricsDomain(getEnv, cfg.EnableMetrics)
 	if err != nil {
 		return nil, err
 	}
 	cfg.MetricsDomain = metricsDomain
 
+	rateLimit, rateLimitBurst, err := getRateLimit(getEnv)
+	if err != nil {
+		return nil, err
+	}
+	cfg.RateLimit = rateLimit
+	cfg.RateLimitBurst = rateLimitBurst
+
+	quotaPerService, err := getNonNegativeInt(getEnv, EnvQuotaPerService)
+	if err != nil {
+		return nil, err
+	}
+	cfg.QuotaPerService = quotaPerService
+
+	quotaPerPlan, err := getNonNegativeInt(getEnv, EnvQuotaPerPlan)
+	if err != nil {
+		return nil, err
+	}
+	cfg.QuotaPerPlan = quotaPerPlan
+
+	sampleRatio, err := getTracingSampleRatio(getEnv)
+	if err != nil {
+		return nil, err
+	}
+	cfg.TracingSampleRatio = sampleRatio
+
+	logLevel, err := getLogLevel(getEnv)
+	if err != nil {
+		return nil, err
+	}
+	cfg.LogLevel = logLevel
+
+	logFormat, err := getLogFormat(getEnv)
+	if err != nil {
+		return nil, err
+	}
+	cfg.LogFormat = logFormat
+
+	overrides, err := getLogLevelOverrides(getEnv)
+	if err != nil {
+		return nil, err
+	}
+	cfg.LogLevelOverrides = overrides
+
 	setDefaults(&cfg)
 
 	return &cfg, nil
@@ -177,20 +346,40 @@ func setDefaults(cfg *Config) {
 
 func ensureRequiredSettings(cfg Config) error {
 	if cfg.Username == "" {
-		return fmt.Errorf("%s is required, but was not defined or is empty", EnvUsername)
+		return settingErrorf(EnvUsername, "is required, but was not defined or is empty")
 	}
 	if cfg.Password == "" {
-		return fmt.Errorf("%s is required, but was not defined or is empty", EnvPassword)
+		return settingErrorf(EnvPassword, "is required, but was not defined or is empty")
 	}
 	if cfg.Namespace == "" {
-		return fmt.Errorf("%s is required, but was not defined or is empty", EnvNamespace)
+		return settingErrorf(EnvNamespace, "is required, but was not defined or is empty")
 	}
 	if cfg.EnableMetrics == true && cfg.MetricsDomain == "" {
-		return fmt.Errorf("%s is required, but was not defined or is empty", EnvMetricsDomain)
+		return settingErrorf(EnvMetricsDomain, "is required, but was not defined or is empty")
+	}
+	if err := validateAuditSink(cfg.AuditSink); err != nil {
+		return err
+	}
+	if cfg.PlanUpdateInlineRules != "" && cfg.PlanUpdateRules != "" {
+		return settingErrorf(EnvPlanUpdateInlineRules, "is set, but %s is set as well", EnvPlanUpdateRules)
+	}
+	if cfg.TLSCertFile != "" && cfg.TLSKeyFile == "" {
+		return settingErrorf(EnvTLSCertFile, "is set, but %s is empty", EnvTLSKeyFile)
+	}
+	if cfg.TLSKeyFile != "" && cfg.TLSCertFile == "" {
+		return settingErrorf(EnvTLSKeyFile, "is set, but %s is empty", EnvTLSCertFile)
+	}
+	if cfg.TLSClientCAFile != "" && cfg.TLSCertFile == "" {
+		return settingErrorf(EnvTLSClientCAFile, "is set, but %s is empty", EnvTLSCertFile)
 	}
 	return nil
 }
 
+// TLSEnabled returns true if the broker is configured to serve HTTPS.
+func (c Config) TLSEnabled() bool {
+	return c.TLSCertFile != ""
+}
+
 func getHTTPMaxHeaderBytes(getEnv GetEnv) (int, error) {
 	var bytes int
 	httpMaxHeaderBytes := getEnv(EnvHTTPMaxHeaderBytes)
@@ -199,7 +388,7 @@ func getHTTPMaxHeaderBytes(getEnv GetEnv) (int, error) {
 	} else {
 		mhb, err := strconv.Atoi(httpMaxHeaderBytes)
 		if err != nil {
-			return 0, fmt.Errorf("%s is set to '%s', but a number was expected: %w", EnvHTTPMaxHeaderBytes, httpMaxHeaderBytes, err)
+			return 0, settingErrorf(EnvHTTPMaxHeaderBytes, "is set to '%s', but a number was expected: %w", httpMaxHeaderBytes, err)
 		}
 		bytes = mhb
 	}
@@ -207,17 +396,23 @@ func getHTTPMaxHeaderBytes(getEnv GetEnv) (int, error) {
 }
 
 func getServiceIDs(getEnv GetEnv) ([]string, error) {
-	var ids []string
-	for _, s := range strings.Split(getEnv(EnvServiceIDs), ",") {
+	ids := splitList(getEnv(EnvServiceIDs))
+	if len(ids) == 0 {
+		return nil, settingErrorf(EnvServiceIDs, "is required, but was not defined or is empty")
+	}
+	return ids, nil
+}
+
+// splitList splits a comma-separated list, ignoring empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, s := range strings.Split(value, ",") {
 		s = strings.TrimSpace(s)
 		if s != "" {
-			ids = append(ids, s)
+			items = append(items, s)
 		}
 	}
-	if len(ids) == 0 {
-		return nil, fmt.Errorf("%s is required, but was not defined or is empty", EnvServiceIDs)
-	}
-	return ids, nil
+	return items
 }
 
 func getTimeout(getEnv GetEnv, timeoutName string) (time.Duration, error) {
@@ -228,7 +423,7 @@ func getTimeout(getEnv GetEnv, timeoutName string) (time.Duration, error) {
 
 	wt, err := time.ParseDuration(timeout)
 	if err != nil {
-		return 0, fmt.Errorf("%s is set to '%s', but that is not a valid time format: %w", timeoutName, timeout, err)
+		return 0, settingErrorf(timeoutName, "is set to '%s', but that is not a valid time format: %w", timeout, err)
 	}
 	return wt, err
 }
@@ -240,18 +435,139 @@ func getEnableMetrics(getEnv GetEnv) (bool, error) {
 	}
 	metricsEnabled, err := strconv.ParseBool(enableMetrics)
 	if err != nil {
-		return false, fmt.Errorf("%s is set to '%t', but a boolean was expected: %w", EnvEnableMetrics, metricsEnabled, err)
+		return false, settingErrorf(EnvEnableMetrics, "is set to '%t', but a boolean was expected: %w", metricsEnabled, err)
 	}
 	return metricsEnabled, nil
 }
 
+func getBindingSecrets(getEnv GetEnv) (bool, error) {
+	v := getEnv(EnvBindingSecrets)
+	if v == "" {
+		return defaultBindingSecrets, nil
+	}
+	enabled, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, settingErrorf(EnvBindingSecrets, "is set to '%s', but a boolean was expected: %w", v, err)
+	}
+	return enabled, nil
+}
+
+// getRateLimit returns the configured rate limit and burst. A rate limit of 0 disables rate limiting.
+// If no burst is configured, it defaults to the rate limit rounded up, but at least 1.
+func getRateLimit(getEnv GetEnv) (float64, int, error) {
+	rateLimit := getEnv(EnvRateLimit)
+	if rateLimit == "" {
+		return 0, 0, nil
+	}
+	limit, err := strconv.ParseFloat(rateLimit, 64)
+	if err != nil || limit < 0 {
+		return 0, 0, settingErrorf(EnvRateLimit, "is set to '%s', but a non-negative number was expected", rateLimit)
+	}
+
+	burst, err := getNonNegativeInt(getEnv, EnvRateLimitBurst)
+	if err != nil {
+		return 0, 0, err
+	}
+	if burst == 0 {
+		burst = int(math.Max(1, math.Ceil(limit)))
+	}
+	return limit, burst, nil
+}
+
+func getTracingSampleRatio(getEnv GetEnv) (float64, error) {
+	sampleRatio := getEnv(EnvTracingSampleRatio)
+	if sampleRatio == "" {
+		return defaultTracingSampleRatio, nil
+	}
+	ratio, err := strconv.ParseFloat(sampleRatio, 64)
+	if err != nil || ratio < 0 || ratio > 1 {
+		return 0, settingErrorf(EnvTracingSampleRatio, "is set to '%s', but a number between 0 and 1 was expected", sampleRatio)
+	}
+	return ratio, nil
+}
+
+func validateAuditSink(sink string) error {
+	if sink == "" || sink == AuditSinkKubernetesEvents {
+		return nil
+	}
+	u, err := url.Parse(sink)
+	if err == nil {
+		switch {
+		case u.Scheme == "file" && u.Path != "":
+			return nil
+		case (u.Scheme == "http" || u.Scheme == "https") && u.Host != "":
+			return nil
+		}
+	}
+	return settingErrorf(EnvAuditSink, "is set to '%s', but a file:// or http(s):// URL or '%s' was expected", sink, AuditSinkKubernetesEvents)
+}
+
+// logLevels describes the valid values of log levels in errors.
+const logLevels = "one of 'debug', 'info', 'error' or 'fatal'"
+
+func getLogLevel(getEnv GetEnv) (lager.LogLevel, error) {
+	level := getEnv(EnvLogLevel)
+	if level == "" {
+		return defaultLogLevel, nil
+	}
+	l, err := lager.LogLevelFromString(strings.ToLower(level))
+	if err != nil {
+		return 0, settingErrorf(EnvLogLevel, "is set to '%s', but %s was expected", level, logLevels)
+	}
+	return l, nil
+}
+
+func getLogFormat(getEnv GetEnv) (string, error) {
+	format := getEnv(EnvLogFormat)
+	switch format {
+	case "":
+		return defaultLogFormat, nil
+	case LogFormatPretty, LogFormatJSON, LogFormatLogfmt, LogFormatSlog:
+		return format, nil
+	}
+	return "", settingErrorf(EnvLogFormat, "is set to '%s', but one of '%s', '%s', '%s' or '%s' was expected",
+		format, LogFormatPretty, LogFormatJSON, LogFormatLogfmt, LogFormatSlog)
+}
+
+func getLogLevelOverrides(getEnv GetEnv) (map[string]lager.LogLevel, error) {
+	overrides := map[string]lager.LogLevel{}
+	value := getEnv(EnvLogLevelOverrides)
+	if value == "" {
+		return overrides, nil
+	}
+	for _, o := range strings.Split(value, ",") {
+		component, level, ok := strings.Cut(strings.TrimSpace(o), "=")
+		if !ok || component == "" || level == "" {
+			return nil, settingErrorf(EnvLogLevelOverrides, "contains '%s', but 'component=level' was expected", o)
+		}
+		l, err := lager.LogLevelFromString(strings.ToLower(level))
+		if err != nil {
+			return nil, settingErrorf(EnvLogLevelOverrides, "sets '%s' to '%s', but %s was expected", component, level, logLevels)
+		}
+		overrides[component] = l
+	}
+	return overrides, nil
+}
+
+func getNonNegativeInt(getEnv GetEnv, envVarName string) (int, error) {
+	value := getEnv(envVarName)
+	if value == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, settingErrorf(envVarName, "is set to '%s', but a non-negative number was expected", value)
+	}
+	return i, nil
+}
+
 func getMetricsDomain(GetEnv GetEnv, enableMetrics bool) (string, error) {
 	if !enableMetrics {
 		return "", nil
 	}
 	metricsDomain := GetEnv(EnvMetricsDomain)
 	if metricsDomain == "" {
-		return "", fmt.Errorf("%s is set to true, but %s is empty", EnvEnableMetrics, EnvMetricsDomain)
+		return "", settingErrorf(EnvEnableMetrics, "is set to true, but %s is empty", EnvMetricsDomain)
 	}
 	return metricsDomain, nil
 }
@@ -273,7 +589,7 @@ func loadKeysFromPath(getEnv GetEnv, keys *jwt.KeyRegister, envVarName string, l
 
 	content, err := loadContentFromPath(envVarValue)
 	if err != nil {
-		return fmt.Errorf("unable to load keys from '%s' (defined in %s): %w", envVarValue, envVarName, err)
+		return settingErrorf(envVarName, "is set to '%s', but keys can't be loaded from it: %w", envVarValue, err)
 	}
 
 	_, err = loadFunc(keys, content)
@@ -3,6 +3,7 @@ package config
 import (
 	"testing"
 
+	"code.cloudfoundry.org/lager"
 	"github.com/pascaldekloe/jwt"
 	"github.com/stretchr/testify/assert"
 )
@@ -67,6 +68,236 @@ func Test_ReadConfig(t *testing.T) {
 			config: nil,
 			err:    "ENABLE_METRICS is set to true, but METRICS_DOMAIN is empty",
 		},
+		"tls key required": {
+			env: map[string]string{
+				EnvServiceIDs:  "1,2,3",
+				EnvUsername:    "user",
+				EnvPassword:    "pw",
+				EnvNamespace:   "test",
+				EnvTLSCertFile: "/etc/osb/tls.crt",
+			},
+			config: nil,
+			err:    "OSB_TLS_CERT_FILE is set, but OSB_TLS_KEY_FILE is empty",
+		},
+		"tls certificate required for client ca": {
+			env: map[string]string{
+				EnvServiceIDs:      "1,2,3",
+				EnvUsername:        "user",
+				EnvPassword:        "pw",
+				EnvNamespace:       "test",
+				EnvTLSClientCAFile: "/etc/osb/ca.crt",
+			},
+			config: nil,
+			err:    "OSB_TLS_CLIENT_CA_FILE is set, but OSB_TLS_CERT_FILE is empty",
+		},
+		"tls given": {
+			env: map[string]string{
+				EnvServiceIDs:      "1,2,3",
+				EnvUsername:        "user",
+				EnvPassword:        "pw",
+				EnvNamespace:       "test",
+				EnvTLSCertFile:     "/etc/osb/tls.crt",
+				EnvTLSKeyFile:      "/etc/osb/tls.key",
+				EnvTLSClientCAFile: "/etc/osb/ca.crt",
+			},
+			config: &Config{
+				ServiceIDs:         []string{"1", "2", "3"},
+				ListenAddr:         defaultHTTPListenAddr,
+				Username:           "user",
+				Password:           "pw",
+				UsernameClaim:      defaultUsernameClaim,
+				Namespace:          "test",
+				ReadTimeout:        defaultHTTPTimeout,
+				WriteTimeout:       defaultHTTPTimeout,
+				MaxHeaderBytes:     defaultHTTPMaxHeaderBytes,
+				JWKeyRegister:      &jwt.KeyRegister{},
+				PlanUpdateSLARule:  defaultSLAUpdateRules,
+				TracingSampleRatio: defaultTracingSampleRatio,
+				LogLevel:           defaultLogLevel,
+				LogFormat:          defaultLogFormat,
+				LogLevelOverrides:  map[string]lager.LogLevel{},
+				TLSCertFile:        "/etc/osb/tls.crt",
+				TLSKeyFile:         "/etc/osb/tls.key",
+				TLSClientCAFile:    "/etc/osb/ca.crt",
+			},
+			err: "",
+		},
+		"invalid rate limit": {
+			env: map[string]string{
+				EnvServiceIDs: "1,2,3",
+				EnvUsername:   "user",
+				EnvPassword:   "pw",
+				EnvNamespace:  "test",
+				EnvRateLimit:  "fast",
+			},
+			config: nil,
+			err:    "OSB_RATE_LIMIT is set to 'fast', but a non-negative number was expected",
+		},
+		"negative quota": {
+			env: map[string]string{
+				EnvServiceIDs:   "1,2,3",
+				EnvUsername:     "user",
+				EnvPassword:     "pw",
+				EnvNamespace:    "test",
+				EnvQuotaPerPlan: "-1",
+			},
+			config: nil,
+			err:    "OSB_QUOTA_INSTANCES_PER_PLAN is set to '-1', but a non-negative number was expected",
+		},
+		"rate limit and quotas given": {
+			env: map[string]string{
+				EnvServiceIDs:      "1,2,3",
+				EnvUsername:        "user",
+				EnvPassword:        "pw",
+				EnvNamespace:       "test",
+				EnvRateLimit:       "2.5",
+				EnvQuotaPerService: "10",
+				EnvQuotaPerPlan:    "5",
+			},
+			config: &Config{
+				ServiceIDs:         []string{"1", "2", "3"},
+				ListenAddr:         defaultHTTPListenAddr,
+				Username:           "user",
+				Password:           "pw",
+				UsernameClaim:      defaultUsernameClaim,
+				Namespace:          "test",
+				ReadTimeout:        defaultHTTPTimeout,
+				WriteTimeout:       defaultHTTPTimeout,
+				MaxHeaderBytes:     defaultHTTPMaxHeaderBytes,
+				JWKeyRegister:      &jwt.KeyRegister{},
+				PlanUpdateSLARule:  defaultSLAUpdateRules,
+				TracingSampleRatio: defaultTracingSampleRatio,
+				LogLevel:           defaultLogLevel,
+				LogFormat:          defaultLogFormat,
+				LogLevelOverrides:  map[string]lager.LogLevel{},
+				RateLimit:          2.5,
+				RateLimitBurst:     3,
+				QuotaPerService:    10,
+				QuotaPerPlan:       5,
+			},
+			err: "",
+		},
+		"invalid tracing sample ratio": {
+			env: map[string]string{
+				EnvServiceIDs:         "1,2,3",
+				EnvUsername:           "user",
+				EnvPassword:           "pw",
+				EnvNamespace:          "test",
+				EnvTracingSampleRatio: "2",
+			},
+			config: nil,
+			err:    "OSB_TRACING_SAMPLE_RATIO is set to '2', but a number between 0 and 1 was expected",
+		},
+		"invalid log level": {
+			env: map[string]string{
+				EnvServiceIDs: "1,2,3",
+				EnvUsername:   "user",
+				EnvPassword:   "pw",
+				EnvNamespace:  "test",
+				EnvLogLevel:   "verbose",
+			},
+			config: nil,
+			err:    "OSB_LOG_LEVEL is set to 'verbose', but one of 'debug', 'info', 'error' or 'fatal' was expected",
+		},
+		"invalid log format": {
+			env: map[string]string{
+				EnvServiceIDs: "1,2,3",
+				EnvUsername:   "user",
+				EnvPassword:   "pw",
+				EnvNamespace:  "test",
+				EnvLogFormat:  "xml",
+			},
+			config: nil,
+			err:    "OSB_LOG_FORMAT is set to 'xml', but one of 'pretty', 'json', 'logfmt' or 'slog' was expected",
+		},
+		"invalid log level override": {
+			env: map[string]string{
+				EnvServiceIDs:        "1,2,3",
+				EnvUsername:          "user",
+				EnvPassword:          "pw",
+				EnvNamespace:         "test",
+				EnvLogLevelOverrides: "api=debug,brokerapi",
+			},
+			config: nil,
+			err:    "OSB_LOG_LEVEL_OVERRIDES contains 'brokerapi', but 'component=level' was expected",
+		},
+		"invalid log level of override": {
+			env: map[string]string{
+				EnvServiceIDs:        "1,2,3",
+				EnvUsername:          "user",
+				EnvPassword:          "pw",
+				EnvNamespace:         "test",
+				EnvLogLevelOverrides: "api=verbose",
+			},
+			config: nil,
+			err:    "OSB_LOG_LEVEL_OVERRIDES sets 'api' to 'verbose', but one of 'debug', 'info', 'error' or 'fatal' was expected",
+		},
+		"log settings given": {
+			env: map[string]string{
+				EnvServiceIDs:        "1,2,3",
+				EnvUsername:          "user",
+				EnvPassword:          "pw",
+				EnvNamespace:         "test",
+				EnvLogLevel:          "ERROR",
+				EnvLogFormat:         "logfmt",
+				EnvLogLevelOverrides: "api=debug, brokerapi=info",
+			},
+			config: &Config{
+				ServiceIDs:         []string{"1", "2", "3"},
+				ListenAddr:         defaultHTTPListenAddr,
+				Username:           "user",
+				Password:           "pw",
+				UsernameClaim:      defaultUsernameClaim,
+				Namespace:          "test",
+				ReadTimeout:        defaultHTTPTimeout,
+				WriteTimeout:       defaultHTTPTimeout,
+				MaxHeaderBytes:     defaultHTTPMaxHeaderBytes,
+				JWKeyRegister:      &jwt.KeyRegister{},
+				PlanUpdateSLARule:  defaultSLAUpdateRules,
+				TracingSampleRatio: defaultTracingSampleRatio,
+				LogLevel:           lager.ERROR,
+				LogFormat:          LogFormatLogfmt,
+				LogLevelOverrides: map[string]lager.LogLevel{
+					"api":       lager.DEBUG,
+					"brokerapi": lager.INFO,
+				},
+				EnableMetrics: defaultEnableMetrics,
+			},
+			err: "",
+		},
+		"invalid audit sink": {
+			env: map[string]string{
+				EnvServiceIDs: "1,2,3",
+				EnvUsername:   "user",
+				EnvPassword:   "pw",
+				EnvNamespace:  "test",
+				EnvAuditSink:  "syslog://localhost",
+			},
+			config: nil,
+			err:    "OSB_AUDIT_SINK is set to 'syslog://localhost', but a file:// or http(s):// URL or 'kubernetes-events' was expected",
+		},
+		"invalid binding secrets": {
+			env: map[string]string{
+				EnvServiceIDs:     "1,2,3",
+				EnvUsername:       "user",
+				EnvPassword:       "pw",
+				EnvNamespace:      "test",
+				EnvBindingSecrets: "sometimes",
+			},
+			config: nil,
+			err:    "OSB_BINDING_SECRETS_ENABLED is set to 'sometimes', but a boolean was expected: strconv.ParseBool: parsing \"sometimes\": invalid syntax",
+		},
+		"binding secrets without namespaces": {
+			env: map[string]string{
+				EnvServiceIDs:     "1,2,3",
+				EnvUsername:       "user",
+				EnvPassword:       "pw",
+				EnvNamespace:      "test",
+				EnvBindingSecrets: "true",
+			},
+			config: nil,
+			err:    "OSB_BINDING_SECRETS_NAMESPACES is required, but was not defined or is empty",
+		},
 		"username claim given": {
 			env: map[string]string{
 				EnvServiceIDs:    "1,2,3",
@@ -76,17 +307,21 @@ func Test_ReadConfig(t *testing.T) {
 				EnvUsernameClaim: "different than default",
 			},
 			config: &Config{
-				ServiceIDs:        []string{"1", "2", "3"},
-				ListenAddr:        ":8080",
-				Username:          "user",
-				Password:          "pw",
-				UsernameClaim:     "different than default",
-				Namespace:         "test",
-				ReadTimeout:       defaultHTTPTimeout,
-				WriteTimeout:      defaultHTTPTimeout,
-				MaxHeaderBytes:    defaultHTTPMaxHeaderBytes,
-				JWKeyRegister:     &jwt.KeyRegister{},
-				PlanUpdateSLARule: defaultSLAUpdateRules,
+				ServiceIDs:         []string{"1", "2", "3"},
+				ListenAddr:         ":8080",
+				Username:           "user",
+				Password:           "pw",
+				UsernameClaim:      "different than default",
+				Namespace:          "test",
+				ReadTimeout:        defaultHTTPTimeout,
+				WriteTimeout:       defaultHTTPTimeout,
+				MaxHeaderBytes:     defaultHTTPMaxHeaderBytes,
+				JWKeyRegister:      &jwt.KeyRegister{},
+				PlanUpdateSLARule:  defaultSLAUpdateRules,
+				TracingSampleRatio: defaultTracingSampleRatio,
+				LogLevel:           defaultLogLevel,
+				LogFormat:          defaultLogFormat,
+				LogLevelOverrides:  map[string]lager.LogLevel{},
 			},
 			err: "",
 		},
@@ -100,19 +335,23 @@ func Test_ReadConfig(t *testing.T) {
 				EnvMetricsDomain: "example.tld",
 			},
 			config: &Config{
-				ServiceIDs:        []string{"1", "2", "3"},
-				ListenAddr:        ":8080",
-				Username:          "user",
-				Password:          "pw",
-				UsernameClaim:     defaultUsernameClaim,
-				Namespace:         "test",
-				ReadTimeout:       defaultHTTPTimeout,
-				WriteTimeout:      defaultHTTPTimeout,
-				MaxHeaderBytes:    defaultHTTPMaxHeaderBytes,
-				JWKeyRegister:     &jwt.KeyRegister{},
-				PlanUpdateSLARule: defaultSLAUpdateRules,
-				EnableMetrics:     true,
-				MetricsDomain:     "example.tld",
+				ServiceIDs:         []string{"1", "2", "3"},
+				ListenAddr:         ":8080",
+				Username:           "user",
+				Password:           "pw",
+				UsernameClaim:      defaultUsernameClaim,
+				Namespace:          "test",
+				ReadTimeout:        defaultHTTPTimeout,
+				WriteTimeout:       defaultHTTPTimeout,
+				MaxHeaderBytes:     defaultHTTPMaxHeaderBytes,
+				JWKeyRegister:      &jwt.KeyRegister{},
+				PlanUpdateSLARule:  defaultSLAUpdateRules,
+				TracingSampleRatio: defaultTracingSampleRatio,
+				LogLevel:           defaultLogLevel,
+				LogFormat:          defaultLogFormat,
+				LogLevelOverrides:  map[string]lager.LogLevel{},
+				EnableMetrics:      true,
+				MetricsDomain:      "example.tld",
 			},
 			err: "",
 		},
@@ -124,18 +363,22 @@ func Test_ReadConfig(t *testing.T) {
 				EnvNamespace:  "test",
 			},
 			config: &Config{
-				ServiceIDs:        []string{"1", "2", "3"},
-				ListenAddr:        defaultHTTPListenAddr,
-				Username:          "user",
-				Password:          "pw",
-				UsernameClaim:     defaultUsernameClaim,
-				Namespace:         "test",
-				ReadTimeout:       defaultHTTPTimeout,
-				WriteTimeout:      defaultHTTPTimeout,
-				MaxHeaderBytes:    defaultHTTPMaxHeaderBytes,
-				JWKeyRegister:     &jwt.KeyRegister{},
-				PlanUpdateSLARule: defaultSLAUpdateRules,
-				EnableMetrics:     defaultEnableMetrics,
+				ServiceIDs:         []string{"1", "2", "3"},
+				ListenAddr:         defaultHTTPListenAddr,
+				Username:           "user",
+				Password:           "pw",
+				UsernameClaim:      defaultUsernameClaim,
+				Namespace:          "test",
+				ReadTimeout:        defaultHTTPTimeout,
+				WriteTimeout:       defaultHTTPTimeout,
+				MaxHeaderBytes:     defaultHTTPMaxHeaderBytes,
+				JWKeyRegister:      &jwt.KeyRegister{},
+				PlanUpdateSLARule:  defaultSLAUpdateRules,
+				TracingSampleRatio: defaultTracingSampleRatio,
+				LogLevel:           defaultLogLevel,
+				LogFormat:          defaultLogFormat,
+				LogLevelOverrides:  map[string]lager.LogLevel{},
+				EnableMetrics:      defaultEnableMetrics,
 			},
 			err: "",
 		},
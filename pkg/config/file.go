@@ -0,0 +1,229 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// EnvConfigFile is the path to an optional YAML or JSON config file.
+// Settings of environment variables take precedence over the config file.
+const EnvConfigFile = "OSB_CONFIG_FILE"
+
+// File is the structure of the config file. Every setting corresponds to an environment variable.
+type File struct {
+	Kubeconfig string `json:"kubeconfig"`
+	Namespace  string `json:"namespace"`
+
+	Services struct {
+		IDs []string `json:"ids"`
+	} `json:"services"`
+
+	HTTP struct {
+		ListenAddr     string `json:"listen_addr"`
+		ReadTimeout    string `json:"read_timeout"`
+		WriteTimeout   string `json:"write_timeout"`
+		MaxHeaderBytes *int   `json:"max_header_bytes"`
+	} `json:"http"`
+
+	TLS struct {
+		CertFile     string `json:"cert_file"`
+		KeyFile      string `json:"key_file"`
+		ClientCAFile string `json:"client_ca_file"`
+	} `json:"tls"`
+
+	Auth struct {
+		Username      string `json:"username"`
+		Password      string `json:"password"`
+		UsernameClaim string `json:"username_claim"`
+		JWTKeys       struct {
+			JWKURL string `json:"jwk_url"`
+			PEMURL string `json:"pem_url"`
+		} `json:"jwt_keys"`
+	} `json:"auth"`
+
+	PlanUpdates struct {
+		SizeRules []string `json:"size_rules"`
+		SLARules  []string `json:"sla_rules"`
+		RulesFile string   `json:"rules_file"`
+		// Rules are structured plan update rules in the format of the rules file.
+		Rules json.RawMessage `json:"rules"`
+	} `json:"plan_updates"`
+
+	RateLimit struct {
+		RequestsPerSecond *float64 `json:"requests_per_second"`
+		Burst             *int     `json:"burst"`
+	} `json:"rate_limit"`
+
+	Quotas struct {
+		InstancesPerService *int `json:"instances_per_service"`
+		InstancesPerPlan    *int `json:"instances_per_plan"`
+	} `json:"quotas"`
+
+	Metrics struct {
+		Enabled *bool  `json:"enabled"`
+		Domain  string `json:"domain"`
+	} `json:"metrics"`
+
+	Tracing struct {
+		OTLPEndpoint string   `json:"otlp_endpoint"`
+		SampleRatio  *float64 `json:"sample_ratio"`
+	} `json:"tracing"`
+
+	Logging struct {
+		Level          string            `json:"level"`
+		Format         string            `json:"format"`
+		LevelOverrides map[string]string `json:"level_overrides"`
+	} `json:"logging"`
+
+	Audit struct {
+		Sink string `json:"sink"`
+	} `json:"audit"`
+
+	Bindings struct {
+		Secrets           *bool    `json:"secrets"`
+		SecretsNamespaces []string `json:"secrets_namespaces"`
+	} `json:"bindings"`
+}
+
+// fileSetting is a setting of the config file, rendered in the format of its environment variable.
+type fileSetting struct {
+	key   string
+	value string
+}
+
+// ReadConfigFile reads the config file at path and the env variables using the passed function.
+// Env variables take precedence over settings of the config file, even if they're empty.
+// If path is empty, only env variables are read.
+func ReadConfigFile(path string, lookupEnv LookupEnv) (*Config, error) {
+	getEnv := func(name string) string {
+		v, _ := lookupEnv(name)
+		return v
+	}
+	if path == "" {
+		return ReadConfig(getEnv)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config file: %w", err)
+	}
+	f := File{}
+	if err := yaml.UnmarshalStrict(content, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	settings := f.settings()
+
+	cfg, err := ReadConfig(func(name string) string {
+		if v, ok := lookupEnv(name); ok {
+			return v
+		}
+		return settings[name].value
+	})
+	if err != nil {
+		return nil, fileError(path, settings, lookupEnv, err)
+	}
+	return cfg, nil
+}
+
+// fileError points err at the key of the config file, if err refers to a setting which has been read from the file.
+func fileError(path string, settings map[string]fileSetting, lookupEnv LookupEnv, err error) error {
+	var serr *SettingError
+	if !errors.As(err, &serr) {
+		return err
+	}
+	s, ok := settings[serr.Name]
+	if _, set := lookupEnv(serr.Name); !ok || s.value == "" || set {
+		return err
+	}
+	return fmt.Errorf("%s: %s %w", path, s.key, serr.Err)
+}
+
+func (f File) settings() map[string]fileSetting {
+	s := map[string]fileSetting{}
+	set := func(name, key, value string) {
+		s[name] = fileSetting{key: key, value: value}
+	}
+
+	set(EnvKubeconfig, "kubeconfig", f.Kubeconfig)
+	set(EnvNamespace, "namespace", f.Namespace)
+	set(EnvServiceIDs, "services.ids", strings.Join(f.Services.IDs, ","))
+
+	set(EnvHTTPListenAddr, "http.listen_addr", f.HTTP.ListenAddr)
+	set(EnvHTTPReadTimeout, "http.read_timeout", f.HTTP.ReadTimeout)
+	set(EnvHTTPWriteTimeout, "http.write_timeout", f.HTTP.WriteTimeout)
+	set(EnvHTTPMaxHeaderBytes, "http.max_header_bytes", formatInt(f.HTTP.MaxHeaderBytes))
+
+	set(EnvTLSCertFile, "tls.cert_file", f.TLS.CertFile)
+	set(EnvTLSKeyFile, "tls.key_file", f.TLS.KeyFile)
+	set(EnvTLSClientCAFile, "tls.client_ca_file", f.TLS.ClientCAFile)
+
+	set(EnvUsername, "auth.username", f.Auth.Username)
+	set(EnvPassword, "auth.password", f.Auth.Password)
+	set(EnvUsernameClaim, "auth.username_claim", f.Auth.UsernameClaim)
+	set(EnvJWTKeyJWKURL, "auth.jwt_keys.jwk_url", f.Auth.JWTKeys.JWKURL)
+	set(EnvJWTKeyPEMURL, "auth.jwt_keys.pem_url", f.Auth.JWTKeys.PEMURL)
+
+	set(EnvPlanUpdateSize, "plan_updates.size_rules", strings.Join(f.PlanUpdates.SizeRules, "|"))
+	set(EnvPlanUpdateSLA, "plan_updates.sla_rules", strings.Join(f.PlanUpdates.SLARules, "|"))
+	set(EnvPlanUpdateRules, "plan_updates.rules_file", f.PlanUpdates.RulesFile)
+	set(EnvPlanUpdateInlineRules, "plan_updates.rules", formatJSON(f.PlanUpdates.Rules))
+
+	set(EnvRateLimit, "rate_limit.requests_per_second", formatFloat(f.RateLimit.RequestsPerSecond))
+	set(EnvRateLimitBurst, "rate_limit.burst", formatInt(f.RateLimit.Burst))
+	set(EnvQuotaPerService, "quotas.instances_per_service", formatInt(f.Quotas.InstancesPerService))
+	set(EnvQuotaPerPlan, "quotas.instances_per_plan", formatInt(f.Quotas.InstancesPerPlan))
+
+	set(EnvEnableMetrics, "metrics.enabled", formatBool(f.Metrics.Enabled))
+	set(EnvMetricsDomain, "metrics.domain", f.Metrics.Domain)
+
+	set(EnvTracingEndpoint, "tracing.otlp_endpoint", f.Tracing.OTLPEndpoint)
+	set(EnvTracingSampleRatio, "tracing.sample_ratio", formatFloat(f.Tracing.SampleRatio))
+
+	set(EnvLogLevel, "logging.level", f.Logging.Level)
+	set(EnvLogFormat, "logging.format", f.Logging.Format)
+	overrides := make([]string, 0, len(f.Logging.LevelOverrides))
+	for component, level := range f.Logging.LevelOverrides {
+		overrides = append(overrides, component+"="+level)
+	}
+	set(EnvLogLevelOverrides, "logging.level_overrides", strings.Join(overrides, ","))
+
+	set(EnvAuditSink, "audit.sink", f.Audit.Sink)
+	set(EnvBindingSecrets, "bindings.secrets", formatBool(f.Bindings.Secrets))
+	set(EnvBindingSecretsNamespaces, "bindings.secrets_namespaces", strings.Join(f.Bindings.SecretsNamespaces, ","))
+
+	return s
+}
+
+func formatInt(i *int) string {
+	if i == nil {
+		return ""
+	}
+	return strconv.Itoa(*i)
+}
+
+func formatBool(b *bool) string {
+	if b == nil {
+		return ""
+	}
+	return strconv.FormatBool(*b)
+}
+
+func formatJSON(raw json.RawMessage) string {
+	if string(raw) == "null" {
+		return ""
+	}
+	return string(raw)
+}
+
+func formatFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'g', -1, 64)
+}
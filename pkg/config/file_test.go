@@ -0,0 +1,171 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func givenConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// givenEnv returns a LookupEnv of the variables in env.
+func givenEnv(env map[string]string) LookupEnv {
+	return func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+}
+
+func TestReadConfigFile(t *testing.T) {
+	path := givenConfigFile(t, "broker.yaml", `
+namespace: test
+services:
+  ids: ["1", "2"]
+http:
+  read_timeout: 1m
+  max_header_bytes: 1024
+auth:
+  username: user
+  password: pw
+plan_updates:
+  size_rules:
+    - xsmall>small
+    - small>xsmall
+  rules_file: /etc/osb/plan-updates.yaml
+rate_limit:
+  requests_per_second: 2.5
+logging:
+  level: debug
+  format: json
+  level_overrides:
+    api: error
+`)
+
+	cfg, err := ReadConfigFile(path, givenEnv(map[string]string{
+		EnvPassword:       "env-pw",
+		EnvRateLimitBurst: "5",
+		EnvLogFormat:      "",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "test", cfg.Namespace)
+	assert.Equal(t, []string{"1", "2"}, cfg.ServiceIDs)
+	assert.Equal(t, time.Minute, cfg.ReadTimeout)
+	assert.Equal(t, defaultHTTPTimeout, cfg.WriteTimeout)
+	assert.Equal(t, 1024, cfg.MaxHeaderBytes)
+	assert.Equal(t, "user", cfg.Username)
+	assert.Equal(t, "env-pw", cfg.Password, "env variables must take precedence")
+	assert.Equal(t, "xsmall>small|small>xsmall", cfg.PlanUpdateSizeRule)
+	assert.Equal(t, defaultSLAUpdateRules, cfg.PlanUpdateSLARule)
+	assert.Equal(t, "/etc/osb/plan-updates.yaml", cfg.PlanUpdateRules)
+	assert.Equal(t, 2.5, cfg.RateLimit)
+	assert.Equal(t, 5, cfg.RateLimitBurst)
+	assert.Equal(t, lager.DEBUG, cfg.LogLevel)
+	assert.Equal(t, defaultLogFormat, cfg.LogFormat, "empty env variables must take precedence")
+	assert.Equal(t, map[string]lager.LogLevel{"api": lager.ERROR}, cfg.LogLevelOverrides)
+}
+
+func TestReadConfigFile_InlinePlanUpdateRules(t *testing.T) {
+	path := givenConfigFile(t, "broker.yaml", `
+namespace: test
+services:
+  ids: ["1"]
+auth:
+  username: user
+  password: pw
+plan_updates:
+  rules:
+    size_ladder: [small, large]
+    rules:
+      - name: upgrades
+        size: upgrade
+`)
+
+	cfg, err := ReadConfigFile(path, givenEnv(nil))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"size_ladder":["small","large"],"rules":[{"name":"upgrades","size":"upgrade"}]}`, cfg.PlanUpdateInlineRules)
+
+	_, err = ReadConfigFile(path, givenEnv(map[string]string{EnvPlanUpdateRules: "/etc/osb/plan-updates.yaml"}))
+	assert.EqualError(t, err, path+": plan_updates.rules is set, but OSB_PLAN_UPDATE_RULES_FILE is set as well")
+
+	cfg, err = ReadConfigFile(path, givenEnv(map[string]string{EnvPlanUpdateInlineRules: ""}))
+	require.NoError(t, err)
+	assert.Empty(t, cfg.PlanUpdateInlineRules, "an empty env variable must unset the rules of the file")
+}
+
+func TestReadConfigFile_JSON(t *testing.T) {
+	path := givenConfigFile(t, "broker.json", `{
+  "namespace": "test",
+  "services": {"ids": ["1"]},
+  "auth": {"username": "user", "password": "pw"},
+  "metrics": {"enabled": true, "domain": "example.com"},
+  "bindings": {"secrets": true, "secrets_namespaces": ["team-a", "team-b"]}
+}`)
+
+	cfg, err := ReadConfigFile(path, givenEnv(nil))
+	require.NoError(t, err)
+	assert.True(t, cfg.EnableMetrics)
+	assert.Equal(t, "example.com", cfg.MetricsDomain)
+	assert.True(t, cfg.BindingSecrets)
+	assert.Equal(t, []string{"team-a", "team-b"}, cfg.BindingSecretsNamespaces)
+}
+
+func TestReadConfigFile_Errors(t *testing.T) {
+	tt := map[string]struct {
+		content string
+		env     map[string]string
+		err     string
+		// inFile is true if the error is expected to be prefixed with the path of the config file.
+		inFile bool
+	}{
+		"unknown key": {
+			content: "auth:\n  user: foo\n",
+			err:     `error unmarshaling JSON: while decoding JSON: json: unknown field "user"`,
+			inFile:  true,
+		},
+		"wrong type": {
+			content: "http:\n  max_header_bytes: lots\n",
+			err:     "error unmarshaling JSON: while decoding JSON: json: cannot unmarshal string into Go struct field .http.max_header_bytes of type int",
+			inFile:  true,
+		},
+		"invalid value": {
+			content: "namespace: test\nservices:\n  ids: [\"1\"]\nauth:\n  username: user\n  password: pw\ntracing:\n  sample_ratio: 2\n",
+			err:     "tracing.sample_ratio is set to '2', but a number between 0 and 1 was expected",
+			inFile:  true,
+		},
+		"invalid value overridden by env": {
+			content: "namespace: test\nservices:\n  ids: [\"1\"]\nauth:\n  username: user\n  password: pw\ntracing:\n  sample_ratio: 0.5\n",
+			env:     map[string]string{EnvTracingSampleRatio: "3"},
+			err:     "OSB_TRACING_SAMPLE_RATIO is set to '3', but a number between 0 and 1 was expected",
+		},
+		"invalid value mentioning another setting": {
+			content: "namespace: test\nservices:\n  ids: [\"1\"]\nauth:\n  username: user\n  password: pw\ntls:\n  cert_file: /tls/tls.crt\n",
+			err:     "tls.cert_file is set, but OSB_TLS_KEY_FILE is empty",
+			inFile:  true,
+		},
+		"missing value": {
+			content: "namespace: test\nservices:\n  ids: [\"1\"]\n",
+			err:     "OSB_USERNAME is required, but was not defined or is empty",
+		},
+	}
+	for name, tc := range tt {
+		t.Run(name, func(t *testing.T) {
+			path := givenConfigFile(t, "broker.yaml", tc.content)
+			cfg, err := ReadConfigFile(path, givenEnv(tc.env))
+			assert.Nil(t, cfg)
+			if tc.inFile {
+				tc.err = path + ": " + tc.err
+			}
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package crossplane
+
+import (
+	"context"
+
+	"github.com/pivotal-cf/brokerapi/v8/domain"
+)
+
+// SyslogDrainURLKey is the key in the connection secret of an instance that contains the URL its bound apps send logs to.
+const SyslogDrainURLKey = "syslogDrainUrl"
+
+// BindingExtender enables service implementations to return more than credentials for a binding.
+// Only the extras the service requires permission for in the catalog are passed to the platform.
+type BindingExtender interface {
+	BindingExtras(ctx context.Context, bindingID string) (BindingExtras, error)
+}
+
+// BindingExtras are returned alongside the credentials of a binding.
+type BindingExtras struct {
+	SyslogDrainURL  string
+	RouteServiceURL string
+	VolumeMounts    []domain.VolumeMount
+}
+
+// BindingExtras returns the syslog drain URL from the connection details of the instance, if any.
+func (sb serviceBinder) BindingExtras(ctx context.Context, _ string) (BindingExtras, error) {
+	extras := BindingExtras{}
+	s, err := sb.cp.GetConnectionDetails(ctx, sb.instance.Composite)
+	if err != nil {
+		return extras, err
+	}
+	extras.SyslogDrainURL = string(s.Data[SyslogDrainURLKey])
+	return extras, nil
+}
@@ -0,0 +1,194 @@
+package crossplane
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi/v8/domain/apiresponses"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vshn/crossplane-service-broker/pkg/platform"
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+)
+
+const (
+	// BindingSecretRefKey is the key of the credentials referencing the Secret a binding is delivered in.
+	BindingSecretRefKey = "secretRef"
+
+	bindingSecretName = "binding-%s"
+)
+
+// BindingSecretsEnabled returns true if bindings of Kubernetes platforms are delivered as Secrets.
+func (cp Crossplane) BindingSecretsEnabled() bool {
+	return cp.config().BindingSecrets
+}
+
+// BindingSecretNamespace returns the namespace the credentials of a binding are delivered to as Secret.
+// It's empty if binding secrets are disabled or the request has no Kubernetes platform context.
+// Namespaces which aren't configured in BindingSecretsNamespaces are refused.
+func (cp Crossplane) BindingSecretNamespace(rctx *reqcontext.ReqContext) (string, error) {
+	if !cp.BindingSecretsEnabled() || rctx.Platform.Platform != platform.Kubernetes {
+		return "", nil
+	}
+	ns := rctx.Platform.Namespace
+	for _, allowed := range cp.config().BindingSecretsNamespaces {
+		if ns == allowed {
+			return ns, nil
+		}
+	}
+	return "", apiresponses.NewFailureResponse(
+		fmt.Errorf("credentials can't be delivered to namespace %q", ns),
+		http.StatusForbidden,
+		"binding-secret-namespace-forbidden",
+	)
+}
+
+// WriteBindingSecret writes the credentials of a binding into a Secret in the given namespace and returns credentials
+// only referencing that Secret. String values are stored as they are, all others as JSON.
+// An existing Secret of the binding is updated, which makes binding again idempotent.
+func (cp Crossplane) WriteBindingSecret(rctx *reqcontext.ReqContext, instance *Instance, bindingID, namespace string, creds Credentials) (Credentials, error) {
+	rctx, span := rctx.StartSpan("Crossplane.WriteBindingSecret")
+	defer span.End()
+
+	data := map[string][]byte{}
+	for k, v := range creds {
+		if s, ok := v.(string); ok {
+			data[k] = []byte(s)
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode credential %q: %w", k, err)
+		}
+		data[k] = b
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf(bindingSecretName, bindingID),
+			Namespace: namespace,
+			Labels: map[string]string{
+				InstanceIDLabel: instance.ID(),
+				BindingIDLabel:  bindingID,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+	if id, err := platform.IdentityFromContext(rctx.Context); err == nil {
+		if err := setOriginatingIdentity(secret, id); err != nil {
+			return nil, err
+		}
+	}
+
+	// The namespace is recorded first, so that a Secret written by a failed bind can still be found and deleted.
+	if err := cp.setBindingSecretNamespace(rctx, instance, bindingID, namespace); err != nil {
+		return nil, err
+	}
+
+	err := cp.client.Create(rctx.Context, secret)
+	if k8serrors.IsAlreadyExists(err) {
+		existing := &corev1.Secret{}
+		if err := cp.client.Get(rctx.Context, types.NamespacedName{Name: secret.Name, Namespace: namespace}, existing); err != nil {
+			return nil, err
+		}
+		if existing.Labels[BindingIDLabel] != bindingID {
+			return nil, fmt.Errorf("secret %q in namespace %q does not belong to binding %q", secret.Name, namespace, bindingID)
+		}
+		existing.Data = data
+		err = cp.client.Update(rctx.Context, existing)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not write binding secret: %w", err)
+	}
+	rctx.Logger.Info("binding-secret-written", lager.Data{"binding-id": bindingID, "secret": secret.Name, "namespace": namespace})
+	return bindingSecretReference(secret), nil
+}
+
+// BindingSecretReference returns the credentials referencing the Secret the binding is delivered in.
+// It returns nil if the binding isn't delivered as Secret.
+func (cp Crossplane) BindingSecretReference(rctx *reqcontext.ReqContext, instance *Instance, bindingID string) (Credentials, error) {
+	secret, err := cp.bindingSecret(rctx, instance, bindingID)
+	if err != nil || secret == nil {
+		return nil, err
+	}
+	return bindingSecretReference(secret), nil
+}
+
+// DeleteBindingSecret deletes the Secret the binding is delivered in, if there is one.
+func (cp Crossplane) DeleteBindingSecret(rctx *reqcontext.ReqContext, instance *Instance, bindingID string) error {
+	rctx, span := rctx.StartSpan("Crossplane.DeleteBindingSecret")
+	defer span.End()
+
+	secret, err := cp.bindingSecret(rctx, instance, bindingID)
+	if err != nil {
+		return err
+	}
+	if secret != nil {
+		if err := cp.client.Delete(rctx.Context, secret); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("could not delete binding secret: %w", err)
+		}
+		rctx.Logger.Info("binding-secret-deleted", lager.Data{"binding-id": bindingID, "secret": secret.Name, "namespace": secret.Namespace})
+	}
+	return cp.setBindingSecretNamespace(rctx, instance, bindingID, "")
+}
+
+// bindingSecret gets the Secret of a binding from the namespace recorded on the composite of the instance.
+// It returns nil if there is none, or if the Secret of that name belongs to another binding.
+func (cp Crossplane) bindingSecret(rctx *reqcontext.ReqContext, instance *Instance, bindingID string) (*corev1.Secret, error) {
+	namespace := instance.Composite.GetAnnotations()[BindingSecretAnnotationPrefix+bindingID]
+	if namespace == "" {
+		return nil, nil
+	}
+	secret := &corev1.Secret{}
+	err := cp.client.Get(rctx.Context, types.NamespacedName{Name: fmt.Sprintf(bindingSecretName, bindingID), Namespace: namespace}, secret)
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret.Labels[BindingIDLabel] != bindingID {
+		return nil, nil
+	}
+	return secret, nil
+}
+
+// setBindingSecretNamespace records the namespace of the Secret of a binding on the composite of the instance.
+// An empty namespace removes the record.
+func (cp Crossplane) setBindingSecretNamespace(rctx *reqcontext.ReqContext, instance *Instance, bindingID, namespace string) error {
+	key := BindingSecretAnnotationPrefix + bindingID
+	if instance.Composite.GetAnnotations()[key] == namespace {
+		return nil
+	}
+	patch := client.MergeFrom(instance.Composite.DeepCopy())
+	annotations := instance.Composite.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	if namespace == "" {
+		delete(annotations, key)
+	} else {
+		annotations[key] = namespace
+	}
+	instance.Composite.SetAnnotations(annotations)
+	if err := cp.client.Patch(rctx.Context, instance.Composite.GetUnstructured(), patch); err != nil {
+		return fmt.Errorf("could not record namespace of binding secret: %w", err)
+	}
+	return nil
+}
+
+func bindingSecretReference(secret *corev1.Secret) Credentials {
+	return Credentials{
+		BindingSecretRefKey: map[string]interface{}{
+			"name":      secret.Name,
+			"namespace": secret.Namespace,
+		},
+	}
+}
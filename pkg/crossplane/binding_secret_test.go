@@ -0,0 +1,155 @@
+package crossplane
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composite"
+	"github.com/pivotal-cf/brokerapi/v8/domain/apiresponses"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+	"github.com/vshn/crossplane-service-broker/pkg/platform"
+)
+
+func Test_BindingSecretNamespace(t *testing.T) {
+	tests := map[string]struct {
+		enabled bool
+		pc      platform.Context
+		want    string
+		wantErr string
+	}{
+		"disabled": {
+			pc: platform.Context{Platform: platform.Kubernetes, Namespace: "team-a"},
+		},
+		"kubernetes": {
+			enabled: true,
+			pc:      platform.Context{Platform: platform.Kubernetes, Namespace: "team-a"},
+			want:    "team-a",
+		},
+		"namespace not allowed": {
+			enabled: true,
+			pc:      platform.Context{Platform: platform.Kubernetes, Namespace: "kube-system"},
+			wantErr: `credentials can't be delivered to namespace "kube-system"`,
+		},
+		"cloud foundry": {
+			enabled: true,
+			pc:      platform.Context{Platform: platform.CloudFoundry, SpaceGUID: "space-1"},
+		},
+		"no context": {
+			enabled: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cp := givenCrossplane(t, &config.Config{BindingSecrets: tc.enabled, BindingSecretsNamespaces: []string{"team-a", "team-b"}})
+			rctx := givenRequestContext("alice")
+			rctx.Platform = tc.pc
+			got, err := cp.BindingSecretNamespace(rctx)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				var fr *apiresponses.FailureResponse
+				require.ErrorAs(t, err, &fr)
+				assert.Equal(t, http.StatusForbidden, fr.ValidatedStatusCode(nil))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func Test_BindingSecret(t *testing.T) {
+	cmp := givenComposite("1", "alice", "small-standard")
+	cp := givenCrossplane(t, &config.Config{BindingSecrets: true}, cmp)
+	rctx := givenRequestContext("alice")
+	instance, err := newInstance(cmp)
+	require.NoError(t, err)
+
+	wantRef := Credentials{BindingSecretRefKey: map[string]interface{}{"name": "binding-b1", "namespace": "team-a"}}
+
+	ref, err := cp.WriteBindingSecret(rctx, instance, "b1", "team-a", Credentials{"password": "s3cr3t", "port": 6379})
+	require.NoError(t, err)
+	assert.Equal(t, wantRef, ref)
+
+	ref, err = cp.WriteBindingSecret(rctx, instance, "b1", "team-a", Credentials{"password": "n3w", "port": 6379})
+	require.NoError(t, err, "binding again must update the secret")
+	assert.Equal(t, wantRef, ref)
+
+	secret := &corev1.Secret{}
+	require.NoError(t, cp.client.Get(rctx.Context, types.NamespacedName{Name: "binding-b1", Namespace: "team-a"}, secret))
+	assert.Equal(t, map[string][]byte{"password": []byte("n3w"), "port": []byte("6379")}, secret.Data)
+	assert.Equal(t, "1", secret.Labels[InstanceIDLabel])
+	assert.Equal(t, "b1", secret.Labels[BindingIDLabel])
+
+	stored := givenStoredInstance(t, cp, "1")
+	assert.Equal(t, "team-a", stored.Composite.GetAnnotations()[BindingSecretAnnotationPrefix+"b1"], "the namespace must be recorded on the composite")
+
+	ref, err = cp.BindingSecretReference(rctx, stored, "b1")
+	require.NoError(t, err)
+	assert.Equal(t, wantRef, ref)
+	ref, err = cp.BindingSecretReference(rctx, stored, "b2")
+	require.NoError(t, err)
+	assert.Nil(t, ref, "bindings without secret have no reference")
+
+	require.NoError(t, cp.DeleteBindingSecret(rctx, stored, "b1"))
+	stored = givenStoredInstance(t, cp, "1")
+	assert.NotContains(t, stored.Composite.GetAnnotations(), BindingSecretAnnotationPrefix+"b1")
+	ref, err = cp.BindingSecretReference(rctx, stored, "b1")
+	require.NoError(t, err)
+	assert.Nil(t, ref)
+	assert.NoError(t, cp.DeleteBindingSecret(rctx, stored, "b1"), "deleting a missing secret must succeed")
+}
+
+func Test_WriteBindingSecret_Failure(t *testing.T) {
+	cmp := givenComposite("1", "alice", "small-standard")
+	other := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "binding-b1", Namespace: "team-a", Labels: map[string]string{BindingIDLabel: "b2"}}}
+	cp := givenCrossplane(t, &config.Config{BindingSecrets: true}, cmp, other)
+	rctx := givenRequestContext("alice")
+	instance, err := newInstance(cmp)
+	require.NoError(t, err)
+
+	_, err = cp.WriteBindingSecret(rctx, instance, "b1", "team-a", Credentials{"password": "s3cr3t"})
+	assert.EqualError(t, err, `secret "binding-b1" in namespace "team-a" does not belong to binding "b1"`)
+	assert.Equal(t, "team-a", givenStoredInstance(t, cp, "1").Composite.GetAnnotations()[BindingSecretAnnotationPrefix+"b1"],
+		"the namespace must be recorded before writing, so that the secret can be cleaned up")
+
+	require.NoError(t, cp.DeleteBindingSecret(rctx, instance, "b1"))
+	assert.NoError(t, cp.client.Get(rctx.Context, types.NamespacedName{Name: "binding-b1", Namespace: "team-a"}, &corev1.Secret{}),
+		"the secret of another binding must not be deleted")
+	assert.NotContains(t, givenStoredInstance(t, cp, "1").Composite.GetAnnotations(), BindingSecretAnnotationPrefix+"b1")
+}
+
+func Test_DeleteBindingSecret_Disabled(t *testing.T) {
+	cmp := givenComposite("1", "alice", "small-standard")
+	cmp.SetAnnotations(map[string]string{BindingSecretAnnotationPrefix + "b1": "team-a"})
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "binding-b1", Namespace: "team-a", Labels: map[string]string{BindingIDLabel: "b1"}}}
+	cp := givenCrossplane(t, &config.Config{}, cmp, secret)
+	rctx := givenRequestContext("alice")
+	instance, err := newInstance(cmp)
+	require.NoError(t, err)
+
+	ref, err := cp.BindingSecretReference(rctx, instance, "b1")
+	require.NoError(t, err)
+	assert.NotNil(t, ref, "secrets written before disabling binding secrets must still be referenced")
+
+	require.NoError(t, cp.DeleteBindingSecret(rctx, instance, "b1"))
+	err = cp.client.Get(rctx.Context, types.NamespacedName{Name: "binding-b1", Namespace: "team-a"}, &corev1.Secret{})
+	assert.True(t, k8serrors.IsNotFound(err), "secrets written before disabling binding secrets must be deleted, got %v", err)
+	assert.NotContains(t, givenStoredInstance(t, cp, "1").Composite.GetAnnotations(), BindingSecretAnnotationPrefix+"b1")
+}
+
+func givenStoredInstance(t *testing.T, cp *Crossplane, name string) *Instance {
+	cmp := composite.New(composite.WithGroupVersionKind(givenComposite(name, "", "").GroupVersionKind()))
+	require.NoError(t, cp.client.Get(context.Background(), types.NamespacedName{Name: name}, cmp))
+	instance, err := newInstance(cmp)
+	require.NoError(t, err)
+	return instance
+}
@@ -0,0 +1,51 @@
+package crossplane
+
+import (
+	"context"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	xrv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+)
+
+func Test_BindingExtras(t *testing.T) {
+	tests := map[string]struct {
+		data map[string][]byte
+		want BindingExtras
+	}{
+		"without syslog drain": {
+			data: map[string][]byte{xrv1.ResourceCredentialsSecretEndpointKey: []byte("redis.example.com")},
+			want: BindingExtras{},
+		},
+		"with syslog drain": {
+			data: map[string][]byte{SyslogDrainURLKey: []byte("syslog-tls://logs.example.com:6514")},
+			want: BindingExtras{SyslogDrainURL: "syslog-tls://logs.example.com:6514"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cmp := givenComposite("1", "alice", "small-standard")
+			cmp.SetWriteConnectionSecretToReference(&xrv1.SecretReference{Name: "1", Namespace: "sb-1"})
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "1", Namespace: "sb-1"},
+				Data:       tc.data,
+			}
+			cp := givenCrossplane(t, &config.Config{}, secret)
+			instance, err := newInstance(cmp)
+			require.NoError(t, err)
+
+			var sb ServiceBinder = NewRedisServiceBinder(cp, instance, lager.NewLogger("test"))
+			be, ok := sb.(BindingExtender)
+			require.True(t, ok)
+			got, err := be.BindingExtras(context.Background(), "b1")
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
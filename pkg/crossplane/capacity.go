@@ -0,0 +1,193 @@
+package crossplane
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi/v8/domain/apiresponses"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+	"github.com/vshn/crossplane-service-broker/pkg/tracing"
+)
+
+const (
+	// SizeUnitsAnnotation defines how many size units an instance of a plan consumes on its cluster.
+	// Defaults to 1 if not set.
+	SizeUnitsAnnotation = SynToolsBase + "/size-units"
+	// ClusterMaxInstancesAnnotation defines how many instances may be deployed to the cluster of a plan.
+	ClusterMaxInstancesAnnotation = SynToolsBase + "/cluster-max-instances"
+	// ClusterMaxSizeUnitsAnnotation defines how many size units may be consumed on the cluster of a plan.
+	ClusterMaxSizeUnitsAnnotation = SynToolsBase + "/cluster-max-size-units"
+
+	defaultSizeUnits = 1
+)
+
+// ClusterCapacity describes the limits of a cluster. A limit of 0 means unlimited.
+type ClusterCapacity struct {
+	MaxInstances int
+	MaxSizeUnits int
+}
+
+// ClusterUsage describes how many instances and size units are in use on a cluster.
+type ClusterUsage struct {
+	Instances int
+	SizeUnits int
+}
+
+// SizeUnits returns how many size units an instance of this plan consumes.
+func (p Plan) SizeUnits() (int, error) {
+	return parseIntAnnotation(p.Composition.Annotations, SizeUnitsAnnotation, defaultSizeUnits)
+}
+
+// Cluster returns the name of the cluster instances of this plan are deployed to.
+func (p Plan) Cluster() string {
+	return p.Composition.Labels[ClusterLabel]
+}
+
+// ClusterCapacity returns the capacity of the plan's cluster.
+// Annotations on the Composition take precedence over annotations on the XRD of the service.
+func (cp Crossplane) ClusterCapacity(rctx *reqcontext.ReqContext, plan *Plan) (ClusterCapacity, error) {
+	annotations := map[string]string{}
+
+	xrd, err := cp.ServiceXRD(rctx, plan.Labels.ServiceID)
+	if err != nil {
+		return ClusterCapacity{}, err
+	}
+	if xrd != nil {
+		for k, v := range xrd.XRD.Annotations {
+			annotations[k] = v
+		}
+	}
+	for k, v := range plan.Composition.Annotations {
+		annotations[k] = v
+	}
+
+	maxInstances, err := parseIntAnnotation(annotations, ClusterMaxInstancesAnnotation, 0)
+	if err != nil {
+		return ClusterCapacity{}, err
+	}
+	maxSizeUnits, err := parseIntAnnotation(annotations, ClusterMaxSizeUnitsAnnotation, 0)
+	if err != nil {
+		return ClusterCapacity{}, err
+	}
+	return ClusterCapacity{
+		MaxInstances: maxInstances,
+		MaxSizeUnits: maxSizeUnits,
+	}, nil
+}
+
+// ClusterUsage counts the instances of all configured services deployed to the given cluster.
+// The instance with the name `excludeID` is not counted, which allows checking plan changes of existing instances.
+func (cp Crossplane) ClusterUsage(rctx *reqcontext.ReqContext, cluster, excludeID string) (ClusterUsage, error) {
+	usage := ClusterUsage{}
+
+	plans, err := cp.Plans(rctx, cp.config().ServiceIDs)
+	if err != nil {
+		return usage, err
+	}
+
+	sizeUnits := map[string]int{}
+	gvks := map[schema.GroupVersionKind]bool{}
+	for _, p := range plans {
+		units, err := p.SizeUnits()
+		if err != nil {
+			return usage, err
+		}
+		sizeUnits[p.Labels.ServiceID+"/"+p.Labels.PlanName] = units
+
+		gvk, err := p.GVK()
+		if err != nil {
+			return usage, err
+		}
+		gvks[gvk] = true
+	}
+
+	for gvk := range gvks {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := cp.client.List(rctx.Context, list, client.MatchingLabels{ClusterLabel: cluster}); err != nil {
+			return usage, err
+		}
+		for _, item := range list.Items {
+			if item.GetName() == excludeID || item.GetDeletionTimestamp() != nil {
+				continue
+			}
+			l := item.GetLabels()
+			units, ok := sizeUnits[l[ServiceIDLabel]+"/"+l[PlanNameLabel]]
+			if !ok {
+				units = defaultSizeUnits
+			}
+			usage.Instances++
+			usage.SizeUnits += units
+		}
+	}
+	return usage, nil
+}
+
+// CheckClusterCapacity verifies that an instance of the given plan fits on the plan's cluster.
+// If instance is not nil, it is considered to be moved to the new plan and its current usage is not counted.
+// A FailureResponse with status code 422 is returned if the cluster is full.
+func (cp Crossplane) CheckClusterCapacity(rctx *reqcontext.ReqContext, plan *Plan, instance *Instance) error {
+	rctx, span := rctx.StartSpan("Crossplane.CheckClusterCapacity", tracing.PlanIDAttribute.String(plan.Composition.Name))
+	defer span.End()
+
+	capacity, err := cp.ClusterCapacity(rctx, plan)
+	if err != nil {
+		return err
+	}
+	if capacity.MaxInstances <= 0 && capacity.MaxSizeUnits <= 0 {
+		return nil
+	}
+
+	units, err := plan.SizeUnits()
+	if err != nil {
+		return err
+	}
+
+	cluster := plan.Cluster()
+	excludeID := ""
+	if instance != nil {
+		excludeID = instance.ID()
+	}
+	usage, err := cp.ClusterUsage(rctx, cluster, excludeID)
+	if err != nil {
+		return err
+	}
+
+	logData := lager.Data{"cluster": cluster, "capacity": capacity, "usage": usage, "size-units": units}
+	if capacity.MaxInstances > 0 && usage.Instances+1 > capacity.MaxInstances {
+		rctx.Logger.Info("cluster-capacity-exceeded", logData)
+		return clusterCapacityExceeded(fmt.Errorf("cluster %q has reached its maximum of %d instances", cluster, capacity.MaxInstances))
+	}
+	if capacity.MaxSizeUnits > 0 && usage.SizeUnits+units > capacity.MaxSizeUnits {
+		rctx.Logger.Info("cluster-capacity-exceeded", logData)
+		return clusterCapacityExceeded(fmt.Errorf("cluster %q has %d of %d size units left, but plan %q requires %d",
+			cluster, capacity.MaxSizeUnits-usage.SizeUnits, capacity.MaxSizeUnits, plan.Labels.PlanName, units))
+	}
+	return nil
+}
+
+func clusterCapacityExceeded(err error) error {
+	return apiresponses.NewFailureResponseBuilder(
+		err,
+		http.StatusUnprocessableEntity,
+		"cluster-capacity-exceeded",
+	).WithErrorKey("ClusterCapacityExceeded").Build()
+}
+
+func parseIntAnnotation(annotations map[string]string, name string, def int) (int, error) {
+	v, ok := annotations[name]
+	if !ok || v == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("annotation %q is set to %q, but a non-negative number was expected", name, v)
+	}
+	return i, nil
+}
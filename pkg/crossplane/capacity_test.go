@@ -0,0 +1,102 @@
+package crossplane
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composite"
+	"github.com/pivotal-cf/brokerapi/v8/domain/apiresponses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+)
+
+func Test_CheckClusterCapacity(t *testing.T) {
+	tests := map[string]struct {
+		annotations map[string]string
+		planName    string
+		instanceID  string
+		wantErr     bool
+	}{
+		"no limits": {
+			planName: "large-standard",
+		},
+		"instances left": {
+			annotations: map[string]string{ClusterMaxInstancesAnnotation: "3"},
+			planName:    "small-standard",
+		},
+		"no instances left": {
+			annotations: map[string]string{ClusterMaxInstancesAnnotation: "2"},
+			planName:    "small-standard",
+			wantErr:     true,
+		},
+		"size units left": {
+			annotations: map[string]string{ClusterMaxSizeUnitsAnnotation: "6"},
+			planName:    "small-standard",
+		},
+		"not enough size units left": {
+			annotations: map[string]string{ClusterMaxSizeUnitsAnnotation: "6"},
+			planName:    "large-standard",
+			wantErr:     true,
+		},
+		"resizing existing instance": {
+			annotations: map[string]string{ClusterMaxSizeUnitsAnnotation: "8", ClusterMaxInstancesAnnotation: "2"},
+			planName:    "large-standard",
+			instanceID:  "1",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			small := givenClusterPlan("small-standard", "cluster1", map[string]string{SizeUnitsAnnotation: "1"})
+			large := givenClusterPlan("large-standard", "cluster1", map[string]string{SizeUnitsAnnotation: "4"})
+			plans := map[string]*Plan{"small-standard": small, "large-standard": large}
+			for k, v := range tc.annotations {
+				plans[tc.planName].Composition.Annotations[k] = v
+			}
+
+			cp := givenCrossplane(t, &config.Config{ServiceIDs: []string{"redis"}},
+				small.Composition,
+				large.Composition,
+				givenClusterComposite("1", "small-standard", "cluster1").GetUnstructured(),
+				givenClusterComposite("2", "large-standard", "cluster1").GetUnstructured(),
+				givenClusterComposite("3", "large-standard", "cluster2").GetUnstructured(),
+			)
+
+			var instance *Instance
+			if tc.instanceID != "" {
+				instance = &Instance{Composite: givenClusterComposite(tc.instanceID, "small-standard", "cluster1")}
+			}
+
+			err := cp.CheckClusterCapacity(givenRequestContext("alice"), plans[tc.planName], instance)
+			if !tc.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			var apiErr *apiresponses.FailureResponse
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, http.StatusUnprocessableEntity, apiErr.ValidatedStatusCode(nil))
+		})
+	}
+}
+
+func givenClusterPlan(name, cluster string, annotations map[string]string) *Plan {
+	p := givenPlan(name)
+	p.Composition.SetName(name)
+	p.Composition.SetLabels(map[string]string{
+		ServiceNameLabel: string(RedisService),
+		ServiceIDLabel:   "redis",
+		PlanNameLabel:    name,
+		ClusterLabel:     cluster,
+	})
+	p.Composition.SetAnnotations(annotations)
+	return p
+}
+
+func givenClusterComposite(id, plan, cluster string) *composite.Unstructured {
+	cmp := givenComposite(id, "alice", plan)
+	l := cmp.GetLabels()
+	l[ClusterLabel] = cluster
+	cmp.SetLabels(l)
+	return cmp
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"sync/atomic"
 
 	"code.cloudfoundry.org/lager"
 	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
@@ -25,7 +26,9 @@ import (
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/vshn/crossplane-service-broker/pkg/logging"
 	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+	"github.com/vshn/crossplane-service-broker/pkg/tracing"
 )
 
 // errInstanceNotFound is an instance doesn't exist
@@ -33,7 +36,7 @@ var errInstanceNotFound = errors.New("instance not found")
 
 // Crossplane client to access crossplane resources.
 type Crossplane struct {
-	config *config.Config
+	cfg    *atomic.Pointer[config.Config]
 	client client.Client
 }
 
@@ -72,12 +75,32 @@ func New(brokerConfig *config.Config, restConfig *rest.Config) (*Crossplane, err
 		return nil, err
 	}
 
+	return newCrossplane(brokerConfig, instrumentedClient{k}), nil
+}
+
+func newCrossplane(cfg *config.Config, c client.Client) *Crossplane {
 	cp := Crossplane{
-		client: k,
-		config: brokerConfig,
+		cfg:    &atomic.Pointer[config.Config]{},
+		client: c,
 	}
+	cp.cfg.Store(cfg)
+	return &cp
+}
 
-	return &cp, nil
+// config returns the current configuration.
+// It may be replaced by Reload at any time, so it must be read again instead of being kept around.
+func (cp Crossplane) config() *config.Config {
+	return cp.cfg.Load()
+}
+
+// Reload atomically replaces the service IDs and metrics settings with the ones of cfg.
+// Requests in flight continue to use the settings they already read.
+func (cp Crossplane) Reload(cfg *config.Config) {
+	next := *cp.config()
+	next.ServiceIDs = cfg.ServiceIDs
+	next.EnableMetrics = cfg.EnableMetrics
+	next.MetricsDomain = cfg.MetricsDomain
+	cp.cfg.Store(&next)
 }
 
 // ServiceXRD is a wrapper around a CompositeResourceDefinition (XRD) which represents a service.
@@ -87,13 +110,24 @@ type ServiceXRD struct {
 	Metadata    string
 	Tags        string
 	Description string
+	// PlanUpdateTargets maps plan names to the plan names their instances may be updated to.
+	PlanUpdateTargets map[string][]string
+	// DashboardURL is the template of the dashboard URL of the service's instances, see DashboardData.
+	DashboardURL string
+	// DashboardClient is the JSON of the OAuth client of the dashboard.
+	DashboardClient string
+	// Requires is the JSON list of the permissions the service requires.
+	Requires string
 }
 
 // ServiceXRDs retrieves all defined services (defined by XRDs with the ServiceIDLabel) on the cluster.
 func (cp Crossplane) ServiceXRDs(rctx *reqcontext.ReqContext) ([]*ServiceXRD, error) {
+	rctx, span := rctx.StartSpan("Crossplane.ServiceXRDs")
+	defer span.End()
+
 	xrds := &xv1.CompositeResourceDefinitionList{}
 
-	req, err := labels.NewRequirement(ServiceIDLabel, selection.In, cp.config.ServiceIDs)
+	req, err := labels.NewRequirement(ServiceIDLabel, selection.In, cp.config().ServiceIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -111,21 +145,48 @@ func (cp Crossplane) ServiceXRDs(rctx *reqcontext.ReqContext) ([]*ServiceXRD, er
 		if err != nil {
 			return nil, err
 		}
+		targets, err := parseServiceUpdateTargets(xrd.Annotations)
+		if err != nil {
+			// Ignored like the annotation of a Composition, see newPlan.
+			rctx.Logger.Error("parse-plan-update-targets", err, lager.Data{"xrd": xrd.Name})
+			targets = nil
+		}
 		sxrds[i] = &ServiceXRD{
-			XRD:         xrd,
-			Labels:      l,
-			Metadata:    xrd.Annotations[MetadataAnnotation],
-			Tags:        xrd.Annotations[TagsAnnotation],
-			Description: xrd.Annotations[DescriptionAnnotation],
+			XRD:               xrd,
+			Labels:            l,
+			Metadata:          xrd.Annotations[MetadataAnnotation],
+			Tags:              xrd.Annotations[TagsAnnotation],
+			Description:       xrd.Annotations[DescriptionAnnotation],
+			PlanUpdateTargets: targets,
+			DashboardURL:      xrd.Annotations[DashboardURLAnnotation],
+			DashboardClient:   xrd.Annotations[DashboardClientAnnotation],
+			Requires:          xrd.Annotations[RequiresAnnotation],
 		}
 	}
 
 	return sxrds, nil
 }
 
+// ServiceXRD retrieves the service with the given ID. It returns nil if the service is not defined on the cluster.
+func (cp Crossplane) ServiceXRD(rctx *reqcontext.ReqContext, serviceID string) (*ServiceXRD, error) {
+	xrds, err := cp.ServiceXRDs(rctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, xrd := range xrds {
+		if xrd.Labels.ServiceID == serviceID {
+			return xrd, nil
+		}
+	}
+	return nil, nil
+}
+
 // Plans retrieves all plans per passed service. Plans are deployed Compositions with the ServiceIDLabel
 // assigned. The plans are ordered by name.
 func (cp Crossplane) Plans(rctx *reqcontext.ReqContext, serviceIDs []string) ([]*Plan, error) {
+	rctx, span := rctx.StartSpan("Crossplane.Plans")
+	defer span.End()
+
 	req, err := labels.NewRequirement(ServiceIDLabel, selection.In, serviceIDs)
 	if err != nil {
 		return nil, err
@@ -144,7 +205,7 @@ func (cp Crossplane) Plans(rctx *reqcontext.ReqContext, serviceIDs []string) ([]
 
 	plans := make([]*Plan, len(compositions.Items))
 	for i, c := range compositions.Items {
-		p, err := newPlan(c)
+		p, err := newPlan(rctx, c)
 		if err != nil {
 			return nil, err
 		}
@@ -157,12 +218,15 @@ func (cp Crossplane) Plans(rctx *reqcontext.ReqContext, serviceIDs []string) ([]
 // Plan retrieves a single plan as deployed using a Composition. The planID corresponds to the
 // Compositions name.
 func (cp Crossplane) Plan(rctx *reqcontext.ReqContext, planID string) (*Plan, error) {
+	rctx, span := rctx.StartSpan("Crossplane.Plan", tracing.PlanIDAttribute.String(planID))
+	defer span.End()
+
 	composition := xv1.Composition{}
 	err := cp.client.Get(rctx.Context, types.NamespacedName{Name: planID}, &composition)
 	if err != nil {
 		return nil, err
 	}
-	return newPlan(composition)
+	return newPlan(rctx, composition)
 }
 
 // Instance retrieves an instance based on the given instanceID and plan. Besides the instanceID, the planName
@@ -174,6 +238,9 @@ func (cp Crossplane) Plan(rctx *reqcontext.ReqContext, planID string) (*Plan, er
 //	Ported from PoC code as-is, and errInstanceNotFound is handled as instance really not found, however as the ID exists, how
 //	can we speak about having no instance with that name? It's a UUID after all.
 func (cp Crossplane) Instance(rctx *reqcontext.ReqContext, id string, plan *Plan) (inst *Instance, ok bool, err error) {
+	rctx, span := rctx.StartSpan("Crossplane.Instance", tracing.InstanceIDAttribute.String(id), tracing.PlanIDAttribute.String(plan.Composition.Name))
+	defer span.End()
+
 	gvk, err := plan.GVK()
 	if err != nil {
 		return nil, false, err
@@ -209,7 +276,10 @@ func (cp Crossplane) Instance(rctx *reqcontext.ReqContext, id string, plan *Plan
 // It needs to iterate through all plans and fetch the instance using the supplied GVK.
 // There's probably an optimization to be done here, as this seems fairly shitty, but for now it works.
 func (cp Crossplane) FindInstanceWithoutPlan(rctx *reqcontext.ReqContext, id string) (inst *Instance, p *Plan, ok bool, err error) {
-	plans, err := cp.Plans(rctx, cp.config.ServiceIDs)
+	rctx, span := rctx.StartSpan("Crossplane.FindInstanceWithoutPlan", tracing.InstanceIDAttribute.String(id))
+	defer span.End()
+
+	plans, err := cp.Plans(rctx, cp.config().ServiceIDs)
 	if err != nil {
 		return nil, nil, false, err
 	}
@@ -232,6 +302,9 @@ func (cp Crossplane) FindInstanceWithoutPlan(rctx *reqcontext.ReqContext, id str
 
 // CreateInstance sets a new composite with assigned plan and params up.
 func (cp Crossplane) CreateInstance(rctx *reqcontext.ReqContext, id string, plan *Plan, params map[string]interface{}) error {
+	rctx, span := rctx.StartSpan("Crossplane.CreateInstance", tracing.InstanceIDAttribute.String(id), tracing.PlanIDAttribute.String(plan.Composition.Name))
+	defer span.End()
+
 	l, err := cp.prepareLabels(rctx, id, plan, params)
 	if err != nil {
 		return err
@@ -252,12 +325,22 @@ func (cp Crossplane) CreateInstance(rctx *reqcontext.ReqContext, id string, plan
 		return err
 	}
 	cmp.SetLabels(l)
-	rctx.Logger.Debug("create-instance", lager.Data{"instance": cmp})
-	return cp.client.Create(rctx.Context, cmp)
+	if err := setPlatformContext(cmp, rctx.Platform); err != nil {
+		return err
+	}
+	if err := setOriginatingIdentity(cmp, rctx.OriginatingIdentity); err != nil {
+		return err
+	}
+	rctx.Logger.Debug("create-instance", lager.Data{"instance": logging.Redact(cmp)})
+	if err := cp.client.Create(rctx.Context, cmp); err != nil {
+		return err
+	}
+	cp.recordActionEvent(rctx, cmp, EventReasonProvisioned, fmt.Sprintf("instance provisioned with plan %q", plan.Composition.Name))
+	return nil
 }
 
 func (cp Crossplane) prepareLabels(rctx *reqcontext.ReqContext, id string, plan *Plan, params map[string]interface{}) (map[string]string, error) {
-	principal, err := auth.PrincipalFromContext(rctx.Context, cp.config)
+	principal, err := auth.PrincipalFromContext(rctx.Context, cp.config())
 	if err != nil {
 		return nil, err
 	}
@@ -282,8 +365,11 @@ func (cp Crossplane) prepareLabels(rctx *reqcontext.ReqContext, id string, plan
 	return l, nil
 }
 
-// UpdateInstance updates `instance` on k8s.
+// UpdateInstance updates `instance` on k8s, assigning it to `plan` and setting its parameters to `params`.
 func (cp *Crossplane) UpdateInstance(rctx *reqcontext.ReqContext, instance *Instance, plan *Plan, params map[string]any) error {
+	rctx, span := rctx.StartSpan("Crossplane.UpdateInstance", tracing.InstanceIDAttribute.String(instance.ID()), tracing.PlanIDAttribute.String(plan.Composition.Name))
+	defer span.End()
+
 	gvk, err := plan.GVK()
 	if err != nil {
 		return err
@@ -294,20 +380,54 @@ func (cp *Crossplane) UpdateInstance(rctx *reqcontext.ReqContext, instance *Inst
 		return err
 	}
 
-	return cp.client.Update(rctx.Context, instance.Composite.GetUnstructured())
+	previousPlan := ""
+	if ref := instance.Composite.GetCompositionReference(); ref != nil {
+		previousPlan = ref.Name
+	}
+	instance.Composite.SetCompositionReference(&corev1.ObjectReference{
+		Name: plan.Composition.GetName(),
+	})
+	instanceLabels := instance.Composite.GetLabels()
+	for _, l := range []string{
+		PlanNameLabel,
+		SLALabel,
+	} {
+		instanceLabels[l] = plan.Composition.Labels[l]
+	}
+	instance.Composite.SetLabels(instanceLabels)
+	if err := setPlatformContext(instance.Composite, rctx.Platform); err != nil {
+		return err
+	}
+
+	if err := cp.client.Update(rctx.Context, instance.Composite.GetUnstructured()); err != nil {
+		return err
+	}
+	if previousPlan != plan.Composition.Name {
+		cp.recordActionEvent(rctx, instance.Composite, EventReasonPlanChanged, fmt.Sprintf("plan changed from %q to %q", previousPlan, plan.Composition.Name))
+	}
+	return nil
 }
 
 // DeleteInstance deletes a service instance
 func (cp *Crossplane) DeleteInstance(rctx *reqcontext.ReqContext, instanceName string, plan *Plan) error {
+	rctx, span := rctx.StartSpan("Crossplane.DeleteInstance", tracing.InstanceIDAttribute.String(instanceName), tracing.PlanIDAttribute.String(plan.Composition.Name))
+	defer span.End()
+
 	gvk, err := plan.GVK()
 	if err != nil {
 		return err
 	}
 
 	cmp := composite.New(composite.WithGroupVersionKind(gvk))
-	cmp.SetName(instanceName)
+	if err := cp.client.Get(rctx.Context, types.NamespacedName{Name: instanceName}, cmp); err != nil {
+		return err
+	}
 
-	return cp.client.Delete(rctx.Context, cmp)
+	if err := cp.client.Delete(rctx.Context, cmp); err != nil {
+		return err
+	}
+	cp.recordActionEvent(rctx, cmp, EventReasonDeprovisioned, "instance deprovisioned")
+	return nil
 }
 
 // GetConnectionDetails returns the connection details of an instance
@@ -0,0 +1,64 @@
+package crossplane
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+)
+
+func Test_Reload(t *testing.T) {
+	cfg := &config.Config{
+		Namespace:  "osb",
+		ServiceIDs: []string{"redis"},
+	}
+	cp := givenCrossplane(t, cfg)
+	before := cp.config()
+
+	cp.Reload(&config.Config{
+		Namespace:     "other",
+		ServiceIDs:    []string{"redis", "mariadb"},
+		EnableMetrics: true,
+		MetricsDomain: "example.com",
+	})
+
+	assert.Equal(t, &config.Config{
+		Namespace:     "osb",
+		ServiceIDs:    []string{"redis", "mariadb"},
+		EnableMetrics: true,
+		MetricsDomain: "example.com",
+	}, cp.config())
+	assert.Equal(t, []string{"redis"}, before.ServiceIDs, "settings read before the reload must not change")
+	assert.Equal(t, []string{"redis"}, cfg.ServiceIDs, "the initial config must not be modified")
+}
+
+func Test_InvalidPlanUpdateTargets(t *testing.T) {
+	xrd := givenLintXRD("redis", "redis", RedisService, map[string]string{PlanUpdateTargetsAnnotation: `["small-*"]`})
+	comp := givenLintComposition("redis-small", "redis", RedisService, "small-standard", SLAStandard)
+	comp.Annotations[PlanUpdateTargetsAnnotation] = "medium-standard"
+	cp := givenCrossplane(t, &config.Config{ServiceIDs: []string{"redis"}}, &xrd, &comp)
+	rctx := givenRequestContext("alice")
+
+	xrds, err := cp.ServiceXRDs(rctx)
+	require.NoError(t, err, "an invalid annotation must not break the catalog")
+	require.Len(t, xrds, 1)
+	assert.Nil(t, xrds[0].PlanUpdateTargets)
+
+	plans, err := cp.Plans(rctx, []string{"redis"})
+	require.NoError(t, err, "an invalid annotation must not break the catalog")
+	require.Len(t, plans, 1)
+	assert.Nil(t, plans[0].UpdateTargets)
+
+	problems, err := cp.Lint(rctx)
+	require.NoError(t, err)
+	reported := []string{}
+	for _, p := range problems {
+		if strings.Contains(p.Message, PlanUpdateTargetsAnnotation) {
+			reported = append(reported, p.Name)
+		}
+	}
+	assert.ElementsMatch(t, []string{"redis", "redis-small"}, reported, "invalid annotations must be reported by lint")
+}
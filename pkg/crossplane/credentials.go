@@ -0,0 +1,104 @@
+package crossplane
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+)
+
+// CredentialsData is passed to the credential templates.
+type CredentialsData struct {
+	// Credentials are the credentials the service returns by default.
+	Credentials Credentials
+	// Secret is the connection secret of the instance, not of the binding. It's empty if the instance has none.
+	// Values which are specific to a binding, like the user and password of MariaDB bindings, are only part of Credentials.
+	Secret map[string]string
+
+	InstanceID    string
+	BindingID     string
+	ServiceID     string
+	PlanName      string
+	Cluster       string
+	MetricsDomain string
+}
+
+var credentialsTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// CredentialsTemplate parses the credentials template of the plan or, if the plan has none, of its service.
+// It returns nil if neither defines a template.
+// Binding calls it before creating the binding, so that an invalid template doesn't leave a binding behind.
+func (cp Crossplane) CredentialsTemplate(rctx *reqcontext.ReqContext, plan *Plan) (*template.Template, error) {
+	tmpl, err := cp.planOrServiceAnnotation(rctx, plan, CredentialsTemplateAnnotation)
+	if err != nil || tmpl == "" {
+		return nil, err
+	}
+	return parseCredentialsTemplate(tmpl)
+}
+
+// RenderCredentials shapes the credentials of a binding with the template returned by CredentialsTemplate.
+// The given default credentials are returned if the template is nil.
+func (cp Crossplane) RenderCredentials(rctx *reqcontext.ReqContext, tmpl *template.Template, plan *Plan, instance *Instance, bindingID string, creds Credentials) (Credentials, error) {
+	if tmpl == nil {
+		return creds, nil
+	}
+
+	data := CredentialsData{
+		Credentials:   creds,
+		Secret:        map[string]string{},
+		InstanceID:    instance.ID(),
+		BindingID:     bindingID,
+		ServiceID:     plan.Labels.ServiceID,
+		PlanName:      plan.Labels.PlanName,
+		Cluster:       instance.GetClusterName(),
+		MetricsDomain: cp.config().MetricsDomain,
+	}
+	if instance.Composite.GetWriteConnectionSecretToReference() != nil {
+		s, err := cp.GetConnectionDetails(rctx.Context, instance.Composite)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range s.Data {
+			data.Secret[k] = string(v)
+		}
+	}
+	return renderCredentials(tmpl, data)
+}
+
+func renderCredentials(t *template.Template, data CredentialsData) (Credentials, error) {
+	b := &strings.Builder{}
+	if err := t.Execute(b, data); err != nil {
+		return nil, fmt.Errorf("could not render annotation %q: %w", CredentialsTemplateAnnotation, err)
+	}
+	creds := Credentials{}
+	if err := json.Unmarshal([]byte(b.String()), &creds); err != nil {
+		return nil, fmt.Errorf("annotation %q does not render a JSON object: %w", CredentialsTemplateAnnotation, err)
+	}
+	return creds, nil
+}
+
+func parseCredentialsTemplate(tmpl string) (*template.Template, error) {
+	t, err := template.New("credentials").Funcs(credentialsTemplateFuncs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("annotation %q is not a valid template: %w", CredentialsTemplateAnnotation, err)
+	}
+	return t, nil
+}
+
+// lintCredentialsTemplate checks that the credentials template parses.
+// It can't be rendered, as the keys of the default credentials and the secret depend on the service.
+func lintCredentialsTemplate(a map[string]string) []string {
+	if tmpl := a[CredentialsTemplateAnnotation]; tmpl != "" {
+		if _, err := parseCredentialsTemplate(tmpl); err != nil {
+			return []string{err.Error()}
+		}
+	}
+	return nil
+}
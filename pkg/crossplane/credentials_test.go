@@ -0,0 +1,85 @@
+package crossplane
+
+import (
+	"testing"
+
+	xrv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+)
+
+func Test_RenderCredentials(t *testing.T) {
+	defaults := Credentials{"host": "db.example.com", "port": 3306, "password": "secret"}
+
+	tests := map[string]struct {
+		template        string
+		serviceTemplate string
+		want            Credentials
+		wantErr         string
+	}{
+		"no template": {
+			want: defaults,
+		},
+		"template of the service": {
+			serviceTemplate: `{"url": {{ json (printf "mysql://%s:%v" .Credentials.host .Credentials.port) }}}`,
+			want:            Credentials{"url": "mysql://db.example.com:3306"},
+		},
+		"template of the plan takes precedence": {
+			template:        `{"DB_HOST": {{ json .Credentials.host }}, "DB_PASSWORD": {{ json .Secret.password }}, "instance": {{ json .InstanceID }}, "binding": {{ json .BindingID }}}`,
+			serviceTemplate: `{}`,
+			want:            Credentials{"DB_HOST": "db.example.com", "DB_PASSWORD": "from-secret", "instance": "1", "binding": "b1"},
+		},
+		"all default credentials": {
+			template: `{{ json .Credentials }}`,
+			want:     Credentials{"host": "db.example.com", "port": float64(3306), "password": "secret"},
+		},
+		"unknown key": {
+			template: `{"user": {{ json .Credentials.user }}}`,
+			wantErr:  `could not render annotation "service.syn.tools/credentials-template"`,
+		},
+		"invalid template": {
+			template: `{"user": {{ json .Credentials.user }`,
+			wantErr:  `annotation "service.syn.tools/credentials-template" is not a valid template`,
+		},
+		"no JSON object": {
+			template: `{{ .Credentials.host }}`,
+			wantErr:  `annotation "service.syn.tools/credentials-template" does not render a JSON object`,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			xrd := givenLintXRD("redis", "redis", RedisService, map[string]string{CredentialsTemplateAnnotation: tc.serviceTemplate})
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "1", Namespace: "sb-1"},
+				Data:       map[string][]byte{xrv1.ResourceCredentialsSecretPasswordKey: []byte("from-secret")},
+			}
+			cp := givenCrossplane(t, &config.Config{ServiceIDs: []string{"redis"}}, &xrd, secret)
+			plan := givenPlan("small-standard")
+			if tc.template != "" {
+				plan.Composition.Annotations = map[string]string{CredentialsTemplateAnnotation: tc.template}
+			}
+			cmp := givenComposite("1", "alice", "small-standard")
+			cmp.SetWriteConnectionSecretToReference(&xrv1.SecretReference{Name: "1", Namespace: "sb-1"})
+			instance, err := newInstance(cmp)
+			require.NoError(t, err)
+
+			rctx := givenRequestContext("alice")
+			tmpl, err := cp.CredentialsTemplate(rctx, plan)
+			var got Credentials
+			if err == nil {
+				got, err = cp.RenderCredentials(rctx, tmpl, plan, instance, "b1", defaults)
+			}
+			if tc.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
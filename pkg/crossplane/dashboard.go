@@ -0,0 +1,151 @@
+package crossplane
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+)
+
+// DashboardData is passed to the dashboard URL templates.
+type DashboardData struct {
+	InstanceID    string
+	ServiceID     string
+	PlanName      string
+	Cluster       string
+	MetricsDomain string
+}
+
+// DashboardURL renders the dashboard URL of an instance of the plan deployed to the given cluster.
+// The template of the plan takes precedence over the one of its service.
+// It returns an empty string if neither defines a template.
+func (cp Crossplane) DashboardURL(rctx *reqcontext.ReqContext, plan *Plan, instanceID, cluster string) (string, error) {
+	tmpl, err := cp.planOrServiceAnnotation(rctx, plan, DashboardURLAnnotation)
+	if err != nil || tmpl == "" {
+		return "", err
+	}
+	return renderDashboardURL(tmpl, DashboardData{
+		InstanceID:    instanceID,
+		ServiceID:     plan.Labels.ServiceID,
+		PlanName:      plan.Labels.PlanName,
+		Cluster:       cluster,
+		MetricsDomain: cp.config().MetricsDomain,
+	})
+}
+
+// DashboardClient is the OAuth client of the dashboard of a service.
+type DashboardClient struct {
+	ID          string
+	Secret      string
+	RedirectURI string
+}
+
+// dashboardClientSpec is the content of the DashboardClientAnnotation.
+// The secret of the client is read from the key of a Secret in the namespace of the service broker,
+// as annotations can be read by anyone allowed to read the XRD.
+type dashboardClientSpec struct {
+	ID          string `json:"id"`
+	RedirectURI string `json:"redirect_uri"`
+	SecretRef   struct {
+		Name string `json:"name"`
+		Key  string `json:"key"`
+	} `json:"secret_ref"`
+	// Secret is only decoded to refuse annotations which contain the secret itself.
+	Secret string `json:"secret"`
+}
+
+// DashboardClient returns the OAuth client of the dashboard of the service.
+// It returns nil if the service doesn't define a dashboard client.
+func (cp Crossplane) DashboardClient(rctx *reqcontext.ReqContext, service *ServiceXRD) (*DashboardClient, error) {
+	if service.DashboardClient == "" {
+		return nil, nil
+	}
+	spec, err := parseDashboardClient(service.DashboardClient)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: cp.config().Namespace, Name: spec.SecretRef.Name}
+	if err := cp.client.Get(rctx.Context, key, secret); err != nil {
+		return nil, fmt.Errorf("could not get secret of the dashboard client: %w", err)
+	}
+	value, ok := secret.Data[spec.SecretRef.Key]
+	if !ok {
+		return nil, fmt.Errorf("secret %q of the dashboard client has no key %q", spec.SecretRef.Name, spec.SecretRef.Key)
+	}
+	return &DashboardClient{
+		ID:          spec.ID,
+		Secret:      string(value),
+		RedirectURI: spec.RedirectURI,
+	}, nil
+}
+
+func parseDashboardClient(raw string) (*dashboardClientSpec, error) {
+	spec := &dashboardClientSpec{}
+	if err := json.Unmarshal([]byte(raw), spec); err != nil {
+		return nil, fmt.Errorf("annotation %q is not a valid JSON object: %w", DashboardClientAnnotation, err)
+	}
+	switch {
+	case spec.ID == "":
+		return nil, fmt.Errorf("annotation %q has no id", DashboardClientAnnotation)
+	case spec.Secret != "":
+		return nil, fmt.Errorf("annotation %q must not contain the secret, reference it with secret_ref", DashboardClientAnnotation)
+	case spec.SecretRef.Name == "" || spec.SecretRef.Key == "":
+		return nil, fmt.Errorf("annotation %q has no secret_ref with name and key", DashboardClientAnnotation)
+	}
+	return spec, nil
+}
+
+// planOrServiceAnnotation returns the annotation of the plan's Composition or, if it isn't set, of the service's XRD.
+func (cp Crossplane) planOrServiceAnnotation(rctx *reqcontext.ReqContext, plan *Plan, key string) (string, error) {
+	if v := plan.Composition.Annotations[key]; v != "" {
+		return v, nil
+	}
+	xrd, err := cp.ServiceXRD(rctx, plan.Labels.ServiceID)
+	if err != nil || xrd == nil {
+		return "", err
+	}
+	return xrd.XRD.Annotations[key], nil
+}
+
+func renderDashboardURL(tmpl string, data DashboardData) (string, error) {
+	t, err := parseDashboardURL(tmpl)
+	if err != nil {
+		return "", err
+	}
+	b := &strings.Builder{}
+	if err := t.Execute(b, data); err != nil {
+		return "", fmt.Errorf("could not render annotation %q: %w", DashboardURLAnnotation, err)
+	}
+	return b.String(), nil
+}
+
+func parseDashboardURL(tmpl string) (*template.Template, error) {
+	t, err := template.New("dashboard-url").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("annotation %q is not a valid template: %w", DashboardURLAnnotation, err)
+	}
+	return t, nil
+}
+
+// lintDashboard checks that the dashboard URL template renders and, if requested, that the dashboard client is valid.
+func lintDashboard(a map[string]string, withClient bool) []string {
+	msgs := []string{}
+	if tmpl := a[DashboardURLAnnotation]; tmpl != "" {
+		if _, err := renderDashboardURL(tmpl, DashboardData{}); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	if raw := a[DashboardClientAnnotation]; withClient && raw != "" {
+		if _, err := parseDashboardClient(raw); err != nil {
+			msgs = append(msgs, err.Error())
+		}
+	}
+	return msgs
+}
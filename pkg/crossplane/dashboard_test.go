@@ -0,0 +1,114 @@
+package crossplane
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+)
+
+func Test_DashboardURL(t *testing.T) {
+	tests := map[string]struct {
+		template        string
+		serviceTemplate string
+		want            string
+		wantErr         bool
+	}{
+		"no template": {
+			want: "",
+		},
+		"template of the service": {
+			serviceTemplate: "https://{{ .MetricsDomain }}/{{ .InstanceID }}",
+			want:            "https://metrics.example.com/1",
+		},
+		"template of the plan takes precedence": {
+			template:        "https://{{ .Cluster }}/{{ .InstanceID }}",
+			serviceTemplate: "https://{{ .MetricsDomain }}/{{ .InstanceID }}",
+			want:            "https://c1/1",
+		},
+		"all fields": {
+			template: "https://grafana.{{ .Cluster }}.{{ .MetricsDomain }}/d/{{ .ServiceID }}?instance={{ .InstanceID }}&plan={{ .PlanName }}",
+			want:     "https://grafana.c1.metrics.example.com/d/redis?instance=1&plan=small-standard",
+		},
+		"invalid template": {
+			template: "https://{{ .Cluster",
+			wantErr:  true,
+		},
+		"unknown field": {
+			template: "https://{{ .Namespace }}",
+			wantErr:  true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			xrd := givenLintXRD("redis", "redis", RedisService, map[string]string{DashboardURLAnnotation: tc.serviceTemplate})
+			cp := givenCrossplane(t, &config.Config{MetricsDomain: "metrics.example.com", ServiceIDs: []string{"redis"}}, &xrd)
+			plan := givenPlan("small-standard")
+			if tc.template != "" {
+				plan.Composition.Annotations = map[string]string{DashboardURLAnnotation: tc.template}
+			}
+
+			got, err := cp.DashboardURL(givenRequestContext("alice"), plan, "1", "c1")
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func Test_DashboardClient(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "redis-dashboard", Namespace: "osb"},
+		Data:       map[string][]byte{"client-secret": []byte("s3cr3t")},
+	}
+	tests := map[string]struct {
+		annotation string
+		want       *DashboardClient
+		wantErr    string
+	}{
+		"no client": {},
+		"secret of the referenced Secret": {
+			annotation: `{"id": "redis-dashboard", "redirect_uri": "https://grafana.example.com", "secret_ref": {"name": "redis-dashboard", "key": "client-secret"}}`,
+			want:       &DashboardClient{ID: "redis-dashboard", Secret: "s3cr3t", RedirectURI: "https://grafana.example.com"},
+		},
+		"secret in the annotation": {
+			annotation: `{"id": "redis-dashboard", "secret": "s3cr3t"}`,
+			wantErr:    `annotation "service.syn.tools/dashboard-client" must not contain the secret, reference it with secret_ref`,
+		},
+		"no id": {
+			annotation: `{"secret_ref": {"name": "redis-dashboard", "key": "client-secret"}}`,
+			wantErr:    `annotation "service.syn.tools/dashboard-client" has no id`,
+		},
+		"no secret_ref": {
+			annotation: `{"id": "redis-dashboard"}`,
+			wantErr:    `annotation "service.syn.tools/dashboard-client" has no secret_ref with name and key`,
+		},
+		"missing key": {
+			annotation: `{"id": "redis-dashboard", "secret_ref": {"name": "redis-dashboard", "key": "secret"}}`,
+			wantErr:    `secret "redis-dashboard" of the dashboard client has no key "secret"`,
+		},
+		"missing Secret": {
+			annotation: `{"id": "redis-dashboard", "secret_ref": {"name": "grafana", "key": "client-secret"}}`,
+			wantErr:    `could not get secret of the dashboard client: secrets "grafana" not found`,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cp := givenCrossplane(t, &config.Config{Namespace: "osb"}, secret.DeepCopy())
+			got, err := cp.DashboardClient(givenRequestContext("alice"), &ServiceXRD{DashboardClient: tc.annotation})
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
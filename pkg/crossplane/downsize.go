@@ -0,0 +1,101 @@
+package crossplane
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/pivotal-cf/brokerapi/v8/domain/apiresponses"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// StorageCapacityAnnotation defines how much storage an instance of a plan provides, as Kubernetes quantity (e.g. `10Gi`).
+	StorageCapacityAnnotation = SynToolsBase + "/storage-capacity"
+
+	// instanceStatusStorageUsagePath is the path to the storage an instance uses, as reported by its composition.
+	instanceStatusStorageUsagePath = "status.usage.storage"
+)
+
+// DownsizeChecker enables service implementations to refuse plan changes if the instance would not fit into the new plan.
+type DownsizeChecker interface {
+	// CheckDownsize returns an error if the instance does not fit into the given plan.
+	CheckDownsize(ctx context.Context, plan *Plan) error
+}
+
+// StorageCapacity returns the storage an instance of this plan provides. It returns nil if the plan does not define it.
+func (p Plan) StorageCapacity() (*resource.Quantity, error) {
+	v, ok := p.Composition.Annotations[StorageCapacityAnnotation]
+	if !ok || v == "" {
+		return nil, nil
+	}
+	q, err := resource.ParseQuantity(v)
+	if err != nil {
+		return nil, fmt.Errorf("annotation %q of plan %q is not a quantity: %w", StorageCapacityAnnotation, p.Labels.PlanName, err)
+	}
+	return &q, nil
+}
+
+// StorageUsage returns the storage an instance uses. It returns nil if the usage is unknown.
+//
+// The usage reported in the composite's status is preferred. Otherwise, the capacity of the instance's
+// PersistentVolumeClaims, labeled with the InstanceIDLabel, is used, as volumes can't be shrunk.
+func (cp Crossplane) StorageUsage(ctx context.Context, instance *Instance) (*resource.Quantity, error) {
+	v, err := fieldpath.Pave(instance.Composite.Object).GetString(instanceStatusStorageUsagePath)
+	if err == nil && v != "" {
+		q, err := resource.ParseQuantity(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s of instance %q is not a quantity: %w", instanceStatusStorageUsagePath, instance.ID(), err)
+		}
+		return &q, nil
+	}
+
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := cp.client.List(ctx, pvcs, client.MatchingLabels{InstanceIDLabel: instance.ID()}); err != nil {
+		return nil, err
+	}
+	if len(pvcs.Items) == 0 {
+		return nil, nil
+	}
+	usage := resource.Quantity{}
+	for _, pvc := range pvcs.Items {
+		q, ok := pvc.Status.Capacity[corev1.ResourceStorage]
+		if !ok {
+			q = pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		}
+		usage.Add(q)
+	}
+	return &usage, nil
+}
+
+// checkStorageFits returns a 422 error if the storage used by the instance exceeds the storage capacity of the plan.
+// Plans without storage capacity and instances with unknown usage pass.
+func (cp Crossplane) checkStorageFits(ctx context.Context, instance *Instance, plan *Plan, logger lager.Logger) error {
+	capacity, err := plan.StorageCapacity()
+	if err != nil || capacity == nil {
+		return err
+	}
+	usage, err := cp.StorageUsage(ctx, instance)
+	if err != nil || usage == nil {
+		return err
+	}
+	if usage.Cmp(*capacity) <= 0 {
+		return nil
+	}
+
+	logger.Info("downsize-refused", lager.Data{
+		"instance-id":      instance.ID(),
+		"plan":             plan.Labels.PlanName,
+		"storage-usage":    usage.String(),
+		"storage-capacity": capacity.String(),
+	})
+	return apiresponses.NewFailureResponseBuilder(
+		fmt.Errorf("instance uses %s of storage, which does not fit into the %s of plan %q", usage, capacity, plan.Labels.PlanName),
+		http.StatusUnprocessableEntity,
+		"downsize-check-failed",
+	).WithErrorKey("InstanceTooLarge").Build()
+}
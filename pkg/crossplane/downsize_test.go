@@ -0,0 +1,96 @@
+package crossplane
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi/v8/domain/apiresponses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+)
+
+func Test_CheckDownsize(t *testing.T) {
+	tests := map[string]struct {
+		capacity string
+		usage    string
+		pvcs     []string
+		wantErr  bool
+	}{
+		"plan without storage capacity": {
+			usage: "20Gi",
+		},
+		"unknown usage": {
+			capacity: "10Gi",
+		},
+		"status usage fits": {
+			capacity: "10Gi",
+			usage:    "8Gi",
+			pvcs:     []string{"50Gi"},
+		},
+		"status usage exceeds capacity": {
+			capacity: "10Gi",
+			usage:    "12Gi",
+			wantErr:  true,
+		},
+		"PVCs fit": {
+			capacity: "10Gi",
+			pvcs:     []string{"5Gi", "5Gi"},
+		},
+		"PVCs exceed capacity": {
+			capacity: "10Gi",
+			pvcs:     []string{"5Gi", "8Gi"},
+			wantErr:  true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cmp := givenComposite("1", "alice", "large-standard")
+			if tc.usage != "" {
+				cmp.Object["status"] = map[string]interface{}{"usage": map[string]interface{}{"storage": tc.usage}}
+			}
+			objs := []client.Object{cmp.GetUnstructured()}
+			for i, size := range tc.pvcs {
+				objs = append(objs, givenPVC(string(rune('a'+i)), "1", size))
+			}
+			cp := givenCrossplane(t, &config.Config{}, objs...)
+
+			instance, err := newInstance(cmp)
+			require.NoError(t, err)
+			plan := givenPlan("small-standard")
+			if tc.capacity != "" {
+				plan.Composition.Annotations = map[string]string{StorageCapacityAnnotation: tc.capacity}
+			}
+
+			err = NewRedisServiceBinder(cp, instance, lager.NewLogger("test")).CheckDownsize(context.Background(), plan)
+			if !tc.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			var apiErr *apiresponses.FailureResponse
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, http.StatusUnprocessableEntity, apiErr.ValidatedStatusCode(nil))
+			assert.Equal(t, "InstanceTooLarge", apiErr.ErrorResponse().(apiresponses.ErrorResponse).Error)
+		})
+	}
+}
+
+func givenPVC(name, instanceID, size string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "sb-" + instanceID,
+			Labels:    map[string]string{InstanceIDLabel: instanceID},
+		},
+		Status: corev1.PersistentVolumeClaimStatus{
+			Capacity: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)},
+		},
+	}
+}
@@ -0,0 +1,113 @@
+package crossplane
+
+import (
+	"fmt"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vshn/crossplane-service-broker/pkg/api/auth"
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+)
+
+// eventSource is the component Kubernetes Events of the service broker are reported by.
+const eventSource = "crossplane-service-broker"
+
+// Reasons of the Kubernetes Events recorded on composites.
+const (
+	EventReasonProvisioned   = "Provisioned"
+	EventReasonPlanChanged   = "PlanChanged"
+	EventReasonDeprovisioned = "Deprovisioned"
+	EventReasonBound         = "Bound"
+	EventReasonUnbound       = "Unbound"
+)
+
+// RecordBindingEvent records that the binding with bindingID has been created (bound is true) or deleted
+// on the composite of the instance.
+func (cp Crossplane) RecordBindingEvent(rctx *reqcontext.ReqContext, instance *Instance, bindingID string, bound bool) {
+	if bound {
+		cp.recordActionEvent(rctx, instance.Composite, EventReasonBound, fmt.Sprintf("binding %q created", bindingID))
+		return
+	}
+	cp.recordActionEvent(rctx, instance.Composite, EventReasonUnbound, fmt.Sprintf("binding %q deleted", bindingID))
+}
+
+// RecordInstanceEvent records a Kubernetes Event on the composite of the given instance.
+func (cp Crossplane) RecordInstanceEvent(rctx *reqcontext.ReqContext, instanceID, planID, eventType, reason, message string) error {
+	plan, err := cp.Plan(rctx, planID)
+	if err != nil {
+		return err
+	}
+	gvk, err := plan.GVK()
+	if err != nil {
+		return err
+	}
+
+	cmp := &metav1.PartialObjectMetadata{}
+	cmp.SetGroupVersionKind(gvk)
+	if err := cp.client.Get(rctx.Context, types.NamespacedName{Name: instanceID}, cmp); err != nil {
+		return err
+	}
+	return cp.recordEvent(rctx, cmp, eventType, reason, message)
+}
+
+// RecordBrokerEvent records a Kubernetes Event on the namespace of the service broker.
+// It's used for events which don't concern an existing composite.
+func (cp Crossplane) RecordBrokerEvent(rctx *reqcontext.ReqContext, eventType, reason, message string) error {
+	ns := &corev1.Namespace{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+		ObjectMeta: metav1.ObjectMeta{Name: cp.config().Namespace},
+	}
+	return cp.recordEvent(rctx, ns, eventType, reason, message)
+}
+
+// recordActionEvent records a Normal event on obj about an action of the principal of the current request.
+// Events are informational only, so failing to record one is logged but not returned.
+func (cp Crossplane) recordActionEvent(rctx *reqcontext.ReqContext, obj client.Object, reason, action string) {
+	principal, err := auth.PrincipalFromContext(rctx.Context, cp.config())
+	if err != nil {
+		principal = "unknown"
+	}
+	msg := fmt.Sprintf("%s by %q (correlation-id: %q)", action, principal, rctx.CorrelationID)
+	if err := cp.recordEvent(rctx, obj, corev1.EventTypeNormal, reason, msg); err != nil {
+		rctx.Logger.Error("record-event", err, lager.Data{"reason": reason, "name": obj.GetName()})
+	}
+}
+
+// recordEvent creates a Kubernetes Event regarding obj.
+// Events of cluster scoped objects like composites are created in the namespace of the service broker.
+func (cp Crossplane) recordEvent(rctx *reqcontext.ReqContext, obj client.Object, eventType, reason, message string) error {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	namespace := obj.GetNamespace()
+	if namespace == "" {
+		namespace = cp.config().Namespace
+	}
+
+	now := metav1.NewTime(time.Now())
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: obj.GetName() + ".",
+			Namespace:    namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion:      gvk.GroupVersion().String(),
+			Kind:            gvk.Kind,
+			Name:            obj.GetName(),
+			Namespace:       obj.GetNamespace(),
+			UID:             obj.GetUID(),
+			ResourceVersion: obj.GetResourceVersion(),
+		},
+		Type:           eventType,
+		Reason:         reason,
+		Message:        message,
+		Source:         corev1.EventSource{Component: eventSource},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	return cp.client.Create(rctx.Context, event)
+}
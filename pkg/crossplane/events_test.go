@@ -0,0 +1,93 @@
+package crossplane
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+)
+
+func Test_recordEvent(t *testing.T) {
+	cp := givenCrossplane(t, &config.Config{Namespace: "osb"})
+	cmp := givenComposite("1", "alice", "small-standard")
+	cmp.SetUID(types.UID("d6ec54c7-32bd-4bb6-9c5a-2e1b4cd0d7d5"))
+
+	rctx := givenRequestContext("alice")
+	require.NoError(t, cp.recordEvent(rctx, cmp, corev1.EventTypeNormal, "Provisioned", "instance provisioned"))
+
+	events := &corev1.EventList{}
+	require.NoError(t, cp.client.List(rctx.Context, events))
+	require.Len(t, events.Items, 1)
+	e := events.Items[0]
+	assert.Equal(t, "osb", e.Namespace)
+	assert.Equal(t, corev1.ObjectReference{
+		APIVersion: "syn.tools/v1alpha1",
+		Kind:       "CompositeRedisInstance",
+		Name:       "1",
+		UID:        "d6ec54c7-32bd-4bb6-9c5a-2e1b4cd0d7d5",
+	}, e.InvolvedObject)
+	assert.Equal(t, corev1.EventTypeNormal, e.Type)
+	assert.Equal(t, "Provisioned", e.Reason)
+	assert.Equal(t, "instance provisioned", e.Message)
+	assert.Equal(t, eventSource, e.Source.Component)
+}
+
+func Test_InstanceEvents(t *testing.T) {
+	givenNamedPlan := func(name string) *Plan {
+		p := givenPlan(name)
+		p.Composition.Name = "redis-" + name
+		p.Composition.Labels = map[string]string{
+			ServiceNameLabel: string(RedisService),
+			ServiceIDLabel:   "redis",
+			PlanNameLabel:    name,
+		}
+		return p
+	}
+	small := givenNamedPlan("small")
+	large := givenNamedPlan("large")
+
+	cp := givenCrossplane(t, &config.Config{Namespace: "osb", UsernameClaim: "sub"})
+	rctx := givenRequestContext("alice")
+	rctx.CorrelationID = "corrid"
+
+	require.NoError(t, cp.CreateInstance(rctx, "1", small, map[string]interface{}{}))
+
+	inst, ok, err := cp.Instance(rctx, "1", small)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoError(t, cp.UpdateInstance(rctx, inst, large, map[string]interface{}{}))
+	assert.Equal(t, "large", inst.Composite.GetLabels()[PlanNameLabel])
+
+	require.NoError(t, cp.DeleteInstance(rctx, "1", large))
+
+	events := &corev1.EventList{}
+	require.NoError(t, cp.client.List(rctx.Context, events))
+	messages := map[string]string{}
+	for _, e := range events.Items {
+		assert.Equal(t, "1", e.InvolvedObject.Name)
+		messages[e.Reason] = e.Message
+	}
+	assert.Equal(t, map[string]string{
+		EventReasonProvisioned:   `instance provisioned with plan "redis-small" by "alice" (correlation-id: "corrid")`,
+		EventReasonPlanChanged:   `plan changed from "redis-small" to "redis-large" by "alice" (correlation-id: "corrid")`,
+		EventReasonDeprovisioned: `instance deprovisioned by "alice" (correlation-id: "corrid")`,
+	}, messages)
+}
+
+func TestCrossplane_RecordBrokerEvent(t *testing.T) {
+	cp := givenCrossplane(t, &config.Config{Namespace: "osb"})
+	rctx := givenRequestContext("alice")
+	require.NoError(t, cp.RecordBrokerEvent(rctx, corev1.EventTypeWarning, "Audit", `instance "1": provision failed`))
+
+	events := &corev1.EventList{}
+	require.NoError(t, cp.client.List(rctx.Context, events))
+	require.Len(t, events.Items, 1)
+	e := events.Items[0]
+	assert.Equal(t, "osb", e.Namespace)
+	assert.Equal(t, corev1.ObjectReference{APIVersion: "v1", Kind: "Namespace", Name: "osb"}, e.InvolvedObject)
+	assert.Equal(t, `instance "1": provision failed`, e.Message)
+}
@@ -25,6 +25,11 @@ func (i Instance) Ready() bool {
 	return i.Composite.GetCondition(xrv1.TypeReady).Status == corev1.ConditionTrue
 }
 
+// Provisioning returns true if the instance is still being created.
+func (i Instance) Provisioning() bool {
+	return i.Composite.GetCondition(xrv1.TypeReady).Reason == xrv1.ReasonCreating
+}
+
 // Parameters returns the specified parameters if available.
 func (i Instance) Parameters() map[string]interface{} {
 	p, err := fieldpath.Pave(i.Composite.Object).GetValue(instanceSpecParamsPath)
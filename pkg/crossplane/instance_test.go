@@ -0,0 +1,31 @@
+package crossplane
+
+import (
+	"testing"
+
+	xrv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstance_Provisioning(t *testing.T) {
+	tests := map[string]struct {
+		reason xrv1.ConditionReason
+		want   bool
+	}{
+		"creating":          {reason: xrv1.ReasonCreating, want: true},
+		"available":         {reason: xrv1.ReasonAvailable},
+		"unavailable":       {reason: xrv1.ReasonUnavailable},
+		"deleting":          {reason: xrv1.ReasonDeleting},
+		"without condition": {},
+		"unknown reason":    {reason: "Pending"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			cmp := givenComposite("1", "alice", "small")
+			if tt.reason != "" {
+				cmp.SetConditions(xrv1.Condition{Type: xrv1.TypeReady, Reason: tt.reason})
+			}
+			assert.Equal(t, tt.want, Instance{Composite: cmp}.Provisioning())
+		})
+	}
+}
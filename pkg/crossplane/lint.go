@@ -0,0 +1,197 @@
+package crossplane
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	xv1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/pivotal-cf/brokerapi/v8/domain"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+)
+
+const (
+	lintKindXRD         = "CompositeResourceDefinition"
+	lintKindComposition = "Composition"
+)
+
+// LintProblem describes an issue of an XRD or Composition which breaks or degrades the catalog.
+type LintProblem struct {
+	Kind    string
+	Name    string
+	Message string
+}
+
+// Error implements the error interface, which allows logging problems directly.
+func (p LintProblem) Error() string {
+	if p.Name == "" {
+		return p.Message
+	}
+	return fmt.Sprintf("%s %q: %s", p.Kind, p.Name, p.Message)
+}
+
+// Lint validates the XRDs and Compositions of the configured services.
+// Contrary to ServiceXRDs and Plans, it does not stop at the first invalid object but returns all problems found.
+func (cp Crossplane) Lint(rctx *reqcontext.ReqContext) ([]LintProblem, error) {
+	rctx, span := rctx.StartSpan("Crossplane.Lint")
+	defer span.End()
+
+	serviceIDs := cp.config().ServiceIDs
+	req, err := labels.NewRequirement(ServiceIDLabel, selection.In, serviceIDs)
+	if err != nil {
+		return nil, err
+	}
+	selector := client.MatchingLabelsSelector{Selector: labels.NewSelector().Add(*req)}
+
+	xrds := &xv1.CompositeResourceDefinitionList{}
+	if err := cp.client.List(rctx.Context, xrds, selector); err != nil {
+		return nil, err
+	}
+	compositions := &xv1.CompositionList{}
+	if err := cp.client.List(rctx.Context, compositions, selector); err != nil {
+		return nil, err
+	}
+
+	return lintCatalog(serviceIDs, xrds.Items, compositions.Items), nil
+}
+
+func lintCatalog(serviceIDs []string, xrds []xv1.CompositeResourceDefinition, compositions []xv1.Composition) []LintProblem {
+	problems := []LintProblem{}
+	report := func(kind, name, format string, args ...interface{}) {
+		problems = append(problems, LintProblem{Kind: kind, Name: name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	services := map[string]ServiceName{}
+	for _, xrd := range xrds {
+		name := xrd.Name
+		for _, msg := range lintLabels(xrd.Labels, ServiceNameLabel, ServiceIDLabel) {
+			report(lintKindXRD, name, "%s", msg)
+		}
+		for _, msg := range lintAnnotations(xrd.Annotations, true) {
+			report(lintKindXRD, name, "%s", msg)
+		}
+		for _, msg := range lintDashboard(xrd.Annotations, true) {
+			report(lintKindXRD, name, "%s", msg)
+		}
+		for _, msg := range lintCredentialsTemplate(xrd.Annotations) {
+			report(lintKindXRD, name, "%s", msg)
+		}
+		if _, err := parseServiceUpdateTargets(xrd.Annotations); err != nil {
+			report(lintKindXRD, name, "%s", err)
+		}
+
+		id := xrd.Labels[ServiceIDLabel]
+		if _, ok := services[id]; ok {
+			report(lintKindXRD, name, "service ID %q is used by more than one XRD", id)
+			continue
+		}
+		services[id] = ServiceName(xrd.Labels[ServiceNameLabel])
+	}
+
+	for _, id := range serviceIDs {
+		if _, ok := services[id]; !ok {
+			report(lintKindXRD, "", "no XRD found for configured service ID %q", id)
+		}
+	}
+
+	sort.Slice(compositions, func(i, j int) bool {
+		return compositions[i].Name < compositions[j].Name
+	})
+	planNames := map[string]string{}
+	for _, c := range compositions {
+		name := c.Name
+		for _, msg := range lintLabels(c.Labels, ServiceNameLabel, ServiceIDLabel, PlanNameLabel, SLALabel) {
+			report(lintKindComposition, name, "%s", msg)
+		}
+		for _, msg := range lintAnnotations(c.Annotations, false) {
+			report(lintKindComposition, name, "%s", msg)
+		}
+		for _, msg := range lintDashboard(c.Annotations, false) {
+			report(lintKindComposition, name, "%s", msg)
+		}
+		for _, msg := range lintCredentialsTemplate(c.Annotations) {
+			report(lintKindComposition, name, "%s", msg)
+		}
+		if _, err := parseUpdateTargets(c.Annotations); err != nil {
+			report(lintKindComposition, name, "%s", err)
+		}
+
+		id := c.Labels[ServiceIDLabel]
+		serviceName, ok := services[id]
+		if !ok {
+			report(lintKindComposition, name, "no XRD found for service ID %q", id)
+		} else if sn := ServiceName(c.Labels[ServiceNameLabel]); sn != serviceName {
+			report(lintKindComposition, name, "service name %q does not match the XRD's service name %q", sn, serviceName)
+		}
+
+		planName, sla := c.Labels[PlanNameLabel], c.Labels[SLALabel]
+		if planName != "" && sla != "" && !strings.HasSuffix(planName, "-"+sla) {
+			report(lintKindComposition, name, "plan name %q does not end with the SLA %q, the plan size cannot be determined", planName, sla)
+		} else if planName != "" && getPlanSize(planName, sla) == "" {
+			report(lintKindComposition, name, "plan name %q results in an empty plan size", planName)
+		}
+
+		key := id + "/" + planName
+		if other, ok := planNames[key]; ok && planName != "" {
+			report(lintKindComposition, name, "plan name %q is already used by Composition %q", planName, other)
+			continue
+		}
+		planNames[key] = name
+	}
+
+	return problems
+}
+
+// lintLabels checks that the required labels are set and that all labels are parseable.
+func lintLabels(l map[string]string, required ...string) []string {
+	msgs := []string{}
+	for _, r := range required {
+		if l[r] == "" {
+			msgs = append(msgs, fmt.Sprintf("required label %q is missing", r))
+		}
+	}
+	if sn := ServiceName(l[ServiceNameLabel]); sn != "" && !sn.IsValid() {
+		msgs = append(msgs, fmt.Sprintf("unknown service name %q", sn))
+	}
+	for _, b := range []string{BindableLabel, UpdatableLabel} {
+		if _, err := parseBoolLabel(l[b], false); err != nil {
+			msgs = append(msgs, fmt.Sprintf("label %q is not a boolean: %q", b, l[b]))
+		}
+	}
+	return msgs
+}
+
+// lintAnnotations checks that the metadata annotation, and the tags and requires annotations if requested, contain valid JSON.
+func lintAnnotations(a map[string]string, withTags bool) []string {
+	msgs := []string{}
+	meta := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(a[MetadataAnnotation]), &meta); err != nil {
+		msgs = append(msgs, fmt.Sprintf("annotation %q is not a valid JSON object: %s", MetadataAnnotation, err))
+	}
+	if !withTags {
+		return msgs
+	}
+	tags := []string{}
+	if err := json.Unmarshal([]byte(a[TagsAnnotation]), &tags); err != nil {
+		msgs = append(msgs, fmt.Sprintf("annotation %q is not a valid JSON list of strings: %s", TagsAnnotation, err))
+	}
+	if raw := a[RequiresAnnotation]; raw != "" {
+		requires := []domain.RequiredPermission{}
+		if err := json.Unmarshal([]byte(raw), &requires); err != nil {
+			msgs = append(msgs, fmt.Sprintf("annotation %q is not a valid JSON list of strings: %s", RequiresAnnotation, err))
+		}
+		for _, r := range requires {
+			switch r {
+			case domain.PermissionSyslogDrain, domain.PermissionRouteForwarding, domain.PermissionVolumeMount:
+			default:
+				msgs = append(msgs, fmt.Sprintf("annotation %q contains the unknown permission %q", RequiresAnnotation, r))
+			}
+		}
+	}
+	return msgs
+}
@@ -0,0 +1,192 @@
+package crossplane
+
+import (
+	"testing"
+
+	xv1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+)
+
+func Test_lintCatalog(t *testing.T) {
+	validAnnotations := map[string]string{
+		MetadataAnnotation: `{"displayName": "Redis"}`,
+		TagsAnnotation:     `["redis"]`,
+	}
+	redisXRD := givenLintXRD("redis", "redis", RedisService, validAnnotations)
+
+	tests := map[string]struct {
+		serviceIDs   []string
+		xrds         []xv1.CompositeResourceDefinition
+		compositions []xv1.Composition
+		want         []string
+	}{
+		"valid catalog": {
+			serviceIDs: []string{"redis"},
+			xrds:       []xv1.CompositeResourceDefinition{redisXRD},
+			compositions: []xv1.Composition{
+				givenLintComposition("redis-small", "redis", RedisService, "small-standard", SLAStandard),
+				givenLintComposition("redis-small-premium", "redis", RedisService, "small-premium", SLAPremium),
+			},
+			want: []string{},
+		},
+		"missing XRD for service ID": {
+			serviceIDs: []string{"redis", "mariadb"},
+			xrds:       []xv1.CompositeResourceDefinition{redisXRD},
+			want:       []string{`no XRD found for configured service ID "mariadb"`},
+		},
+		"invalid XRD": {
+			serviceIDs: []string{"redis"},
+			xrds: []xv1.CompositeResourceDefinition{
+				givenLintXRD("redis", "redis", "memcached", map[string]string{
+					MetadataAnnotation: `{"displayName": `,
+				}),
+			},
+			want: []string{
+				`CompositeResourceDefinition "redis": unknown service name "memcached"`,
+				`CompositeResourceDefinition "redis": annotation "service.syn.tools/metadata" is not a valid JSON object: unexpected end of JSON input`,
+				`CompositeResourceDefinition "redis": annotation "service.syn.tools/tags" is not a valid JSON list of strings: unexpected end of JSON input`,
+			},
+		},
+		"duplicate service ID": {
+			serviceIDs: []string{"redis"},
+			xrds: []xv1.CompositeResourceDefinition{
+				redisXRD,
+				givenLintXRD("redis-2", "redis", RedisService, validAnnotations),
+			},
+			want: []string{`CompositeResourceDefinition "redis-2": service ID "redis" is used by more than one XRD`},
+		},
+		"plan name and SLA mismatch": {
+			serviceIDs: []string{"redis"},
+			xrds:       []xv1.CompositeResourceDefinition{redisXRD},
+			compositions: []xv1.Composition{
+				givenLintComposition("redis-small", "redis", RedisService, "small", SLAStandard),
+				givenLintComposition("redis-premium", "redis", RedisService, "-premium", SLAPremium),
+			},
+			want: []string{
+				`Composition "redis-premium": plan name "-premium" results in an empty plan size`,
+				`Composition "redis-small": plan name "small" does not end with the SLA "standard", the plan size cannot be determined`,
+			},
+		},
+		"duplicate plan names": {
+			serviceIDs: []string{"redis"},
+			xrds:       []xv1.CompositeResourceDefinition{redisXRD},
+			compositions: []xv1.Composition{
+				givenLintComposition("redis-a", "redis", RedisService, "small-standard", SLAStandard),
+				givenLintComposition("redis-b", "redis", RedisService, "small-standard", SLAStandard),
+			},
+			want: []string{`Composition "redis-b": plan name "small-standard" is already used by Composition "redis-a"`},
+		},
+		"invalid plan update targets": {
+			serviceIDs: []string{"redis"},
+			xrds: []xv1.CompositeResourceDefinition{
+				givenLintXRD("redis", "redis", RedisService, map[string]string{
+					MetadataAnnotation:          `{}`,
+					TagsAnnotation:              `[]`,
+					PlanUpdateTargetsAnnotation: `["medium-standard"]`,
+				}),
+			},
+			want: []string{
+				`CompositeResourceDefinition "redis": annotation "service.syn.tools/plan-update-targets" is not a valid JSON object of plan names: json: cannot unmarshal array into Go value of type map[string][]string`,
+			},
+		},
+		"unknown required permission": {
+			serviceIDs: []string{"redis"},
+			xrds: []xv1.CompositeResourceDefinition{
+				givenLintXRD("redis", "redis", RedisService, map[string]string{
+					MetadataAnnotation: `{}`,
+					TagsAnnotation:     `[]`,
+					RequiresAnnotation: `["syslog_drain", "log_drain"]`,
+				}),
+			},
+			want: []string{
+				`CompositeResourceDefinition "redis": annotation "service.syn.tools/requires" contains the unknown permission "log_drain"`,
+			},
+		},
+		"invalid dashboard": {
+			serviceIDs: []string{"redis"},
+			xrds: []xv1.CompositeResourceDefinition{
+				givenLintXRD("redis", "redis", RedisService, map[string]string{
+					MetadataAnnotation:        `{}`,
+					TagsAnnotation:            `[]`,
+					DashboardURLAnnotation:    "https://{{ .Namespace }}",
+					DashboardClientAnnotation: `{"id": "redis-dashboard", "secret": "s3cr3t"}`,
+				}),
+			},
+			want: []string{
+				`CompositeResourceDefinition "redis": could not render annotation "service.syn.tools/dashboard-url": template: dashboard-url:1:11: executing "dashboard-url" at <.Namespace>: can't evaluate field Namespace in type crossplane.DashboardData`,
+				`CompositeResourceDefinition "redis": annotation "service.syn.tools/dashboard-client" must not contain the secret, reference it with secret_ref`,
+			},
+		},
+		"composition with missing labels and other service name": {
+			serviceIDs: []string{"redis"},
+			xrds:       []xv1.CompositeResourceDefinition{redisXRD},
+			compositions: []xv1.Composition{
+				givenLintComposition("redis-small", "redis", MariaDBService, "", ""),
+			},
+			want: []string{
+				`Composition "redis-small": required label "service.syn.tools/plan" is missing`,
+				`Composition "redis-small": required label "service.syn.tools/sla" is missing`,
+				`Composition "redis-small": service name "mariadb-k8s" does not match the XRD's service name "redis-k8s"`,
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := []string{}
+			for _, p := range lintCatalog(tc.serviceIDs, tc.xrds, tc.compositions) {
+				got = append(got, p.Error())
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func Test_Lint(t *testing.T) {
+	xrd := givenLintXRD("redis", "redis", RedisService, nil)
+	comp := givenLintComposition("redis-small", "redis", RedisService, "small-standard", SLAStandard)
+	other := givenLintComposition("mariadb-small", "mariadb", MariaDBService, "small", SLAStandard)
+	cp := givenCrossplane(t, &config.Config{ServiceIDs: []string{"redis"}}, &xrd, &comp, &other)
+
+	problems, err := cp.Lint(givenRequestContext("alice"))
+	require.NoError(t, err)
+	require.Len(t, problems, 2, "only objects of configured services must be linted")
+	assert.Equal(t, "redis", problems[0].Name)
+	assert.Equal(t, "redis", problems[1].Name)
+}
+
+func givenLintXRD(name, id string, serviceName ServiceName, annotations map[string]string) xv1.CompositeResourceDefinition {
+	return xv1.CompositeResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				ServiceIDLabel:   id,
+				ServiceNameLabel: string(serviceName),
+			},
+			Annotations: annotations,
+		},
+	}
+}
+
+func givenLintComposition(name, id string, serviceName ServiceName, planName, sla string) xv1.Composition {
+	l := map[string]string{
+		ServiceIDLabel:   id,
+		ServiceNameLabel: string(serviceName),
+	}
+	if planName != "" {
+		l[PlanNameLabel] = planName
+	}
+	if sla != "" {
+		l[SLALabel] = sla
+	}
+	return xv1.Composition{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      l,
+			Annotations: map[string]string{MetadataAnnotation: "{}"},
+		},
+	}
+}
@@ -19,6 +19,27 @@ const (
 	DeletionTimestampAnnotation = SynToolsBase + "/deletionTimestamp"
 	// TagsAnnotation of the instance
 	TagsAnnotation = SynToolsBase + "/tags"
+	// PlanUpdateTargetsAnnotation declares the plans an instance may be updated to.
+	// On a Composition, it's a JSON list of plan names. On an XRD, it's a JSON object mapping plan names to such lists.
+	// Plan names may contain wildcards.
+	PlanUpdateTargetsAnnotation = SynToolsBase + "/plan-update-targets"
+	// PlatformContextAnnotation stores the platform context of the instance as JSON
+	PlatformContextAnnotation = SynToolsBase + "/platform-context"
+	// OriginatingIdentityAnnotation stores the platform user who created an instance or binding as JSON
+	OriginatingIdentityAnnotation = SynToolsBase + "/originating-identity"
+	// DashboardURLAnnotation is a Go template of the dashboard URL of an instance, see DashboardData.
+	// On an XRD, it applies to all plans of the service which don't define their own.
+	DashboardURLAnnotation = SynToolsBase + "/dashboard-url"
+	// CredentialsTemplateAnnotation is a Go template rendering the credentials of a binding as JSON object, see CredentialsData.
+	// On an XRD, it applies to all plans of the service which don't define their own.
+	CredentialsTemplateAnnotation = SynToolsBase + "/credentials-template"
+	// RequiresAnnotation of the XRD is a JSON list of the permissions the service requires, e.g. `["syslog_drain"]`
+	RequiresAnnotation = SynToolsBase + "/requires"
+	// DashboardClientAnnotation of the XRD is the OAuth client of the service's dashboard as JSON object with `id`, `redirect_uri`
+	// and `secret_ref`, the `name` and `key` of the Secret in the namespace of the service broker containing the client secret
+	DashboardClientAnnotation = SynToolsBase + "/dashboard-client"
+	// BindingSecretAnnotationPrefix of a composite, followed by the ID of one of its bindings, is the namespace the binding is delivered to as Secret
+	BindingSecretAnnotationPrefix = "binding-secret." + SynToolsBase + "/"
 )
 
 const (
@@ -42,6 +63,16 @@ const (
 	UpdatableLabel = SynToolsBase + "/updatable"
 	// DeletedLabel marks an object as deleted to clean up
 	DeletedLabel = SynToolsBase + "/deleted"
+	// PlatformLabel is the platform the instance has been provisioned from, e.g. `cloudfoundry` or `kubernetes`
+	PlatformLabel = SynToolsBase + "/platform"
+	// OrganizationGUIDLabel is the Cloud Foundry organization of the instance
+	OrganizationGUIDLabel = SynToolsBase + "/organization-guid"
+	// SpaceGUIDLabel is the Cloud Foundry space of the instance
+	SpaceGUIDLabel = SynToolsBase + "/space-guid"
+	// NamespaceLabel is the Kubernetes namespace of the instance
+	NamespaceLabel = SynToolsBase + "/namespace"
+	// BindingIDLabel of the Secret a binding is delivered in
+	BindingIDLabel = SynToolsBase + "/binding"
 	// PrincipalLabel stores the username of the entity (person or system) that created the respective resource
 	PrincipalLabel = SynToolsBase + "/principal"
 
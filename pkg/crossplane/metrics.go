@@ -0,0 +1,204 @@
+package crossplane
+
+import (
+	"context"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	xrv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composite"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+	"github.com/vshn/crossplane-service-broker/pkg/tracing"
+)
+
+// instanceCollectorTimeout limits how long collecting the instance metrics may take.
+const instanceCollectorTimeout = 10 * time.Second
+
+var kubernetesRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "osb_kubernetes_client_request_duration_seconds",
+	Help:    "The duration of requests to the Kubernetes API by verb, kind and outcome.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"verb", "kind", "outcome"})
+
+// instrumentedClient is a client.Client which records the latency of all requests to the Kubernetes API
+// and starts a span for each of them.
+type instrumentedClient struct {
+	client.Client
+}
+
+func (c instrumentedClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	ctx, done := c.start(ctx, "get", obj)
+	err := c.Client.Get(ctx, key, obj, opts...)
+	done(err)
+	return err
+}
+
+func (c instrumentedClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	ctx, done := c.start(ctx, "list", list)
+	err := c.Client.List(ctx, list, opts...)
+	done(err)
+	return err
+}
+
+func (c instrumentedClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	ctx, done := c.start(ctx, "create", obj)
+	err := c.Client.Create(ctx, obj, opts...)
+	done(err)
+	return err
+}
+
+func (c instrumentedClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	ctx, done := c.start(ctx, "update", obj)
+	err := c.Client.Update(ctx, obj, opts...)
+	done(err)
+	return err
+}
+
+func (c instrumentedClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	ctx, done := c.start(ctx, "patch", obj)
+	err := c.Client.Patch(ctx, obj, patch, opts...)
+	done(err)
+	return err
+}
+
+func (c instrumentedClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	ctx, done := c.start(ctx, "delete", obj)
+	err := c.Client.Delete(ctx, obj, opts...)
+	done(err)
+	return err
+}
+
+// start starts a span for the request and returns a function which ends it and records the request duration.
+func (c instrumentedClient) start(ctx context.Context, verb string, obj runtime.Object) (context.Context, func(error)) {
+	start := time.Now()
+	kind := c.kind(obj)
+	attrs := []attribute.KeyValue{
+		attribute.String("k8s.verb", verb),
+		attribute.String("k8s.kind", kind),
+	}
+	if o, ok := obj.(client.Object); ok && o.GetName() != "" {
+		attrs = append(attrs, attribute.String("k8s.name", o.GetName()))
+	}
+	ctx, span := tracing.Tracer().Start(ctx, "Kubernetes."+verb,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+
+	return ctx, func(err error) {
+		outcome := "success"
+		if err != nil {
+			outcome = "error"
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		kubernetesRequestDuration.
+			WithLabelValues(verb, kind, outcome).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+func (c instrumentedClient) kind(obj runtime.Object) string {
+	if gvk := obj.GetObjectKind().GroupVersionKind(); gvk.Kind != "" {
+		return gvk.Kind
+	}
+	gvk, err := c.Client.GroupVersionKindFor(obj)
+	if err != nil {
+		return "unknown"
+	}
+	return gvk.Kind
+}
+
+// InstanceCollector is a prometheus.Collector which exposes the number of instances per service, plan and state.
+// The instances are listed from the Kubernetes API on every scrape.
+type InstanceCollector struct {
+	cp     *Crossplane
+	logger lager.Logger
+	desc   *prometheus.Desc
+}
+
+// NewInstanceCollector returns a new InstanceCollector. It has to be registered with a prometheus.Registerer.
+func NewInstanceCollector(cp *Crossplane, logger lager.Logger) *InstanceCollector {
+	return &InstanceCollector{
+		cp:     cp,
+		logger: logger,
+		desc: prometheus.NewDesc(
+			"osb_instances",
+			"The number of service instances by service, plan and state.",
+			[]string{"service", "plan", "state"},
+			nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (ic *InstanceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ic.desc
+}
+
+// Collect implements prometheus.Collector.
+func (ic *InstanceCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), instanceCollectorTimeout)
+	defer cancel()
+	rctx := reqcontext.NewReqContext(ctx, ic.logger, nil)
+
+	plans, err := ic.cp.Plans(rctx, ic.cp.config().ServiceIDs)
+	if err != nil {
+		rctx.Logger.Error("collect-instance-metrics", err)
+		return
+	}
+
+	gvks := map[schema.GroupVersionKind]bool{}
+	for _, p := range plans {
+		gvk, err := p.GVK()
+		if err != nil {
+			rctx.Logger.Error("collect-instance-metrics", err)
+			continue
+		}
+		gvks[gvk] = true
+	}
+
+	type key struct {
+		service, plan, state string
+	}
+	counts := map[key]int{}
+	for gvk := range gvks {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := ic.cp.client.List(rctx.Context, list, client.HasLabels{InstanceIDLabel}); err != nil {
+			rctx.Logger.Error("collect-instance-metrics", err, lager.Data{"kind": gvk.Kind})
+			continue
+		}
+		for _, item := range list.Items {
+			l := item.GetLabels()
+			counts[key{l[ServiceNameLabel], l[PlanNameLabel], instanceState(&item)}]++
+		}
+	}
+
+	for k, v := range counts {
+		ch <- prometheus.MustNewConstMetric(ic.desc, prometheus.GaugeValue, float64(v), k.service, k.plan, k.state)
+	}
+}
+
+// instanceState returns the reason of the Ready condition of the instance, which is one of
+// `Available`, `Creating`, `Unavailable`, `Deleting` or `Unknown`.
+func instanceState(u *unstructured.Unstructured) string {
+	if u.GetDeletionTimestamp() != nil {
+		return string(xrv1.ReasonDeleting)
+	}
+	cmp := composite.Unstructured{Unstructured: *u}
+	if reason := cmp.GetCondition(xrv1.TypeReady).Reason; reason != "" {
+		return string(reason)
+	}
+	return "Unknown"
+}
@@ -0,0 +1,40 @@
+package crossplane
+
+import (
+	"strings"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	xrv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+)
+
+func Test_InstanceCollector(t *testing.T) {
+	plan := givenClusterPlan("small-standard", "cluster1", nil)
+	ready := givenComposite("1", "alice", "small-standard")
+	ready.SetConditions(xrv1.Condition{Type: xrv1.TypeReady, Status: corev1.ConditionTrue, Reason: xrv1.ReasonAvailable})
+	creating := givenComposite("2", "alice", "small-standard")
+	creating.SetConditions(xrv1.Condition{Type: xrv1.TypeReady, Status: corev1.ConditionFalse, Reason: xrv1.ReasonCreating})
+	unknown := givenComposite("3", "bob", "small-standard")
+
+	cp := givenCrossplane(t, &config.Config{ServiceIDs: []string{"redis"}},
+		plan.Composition,
+		ready.GetUnstructured(),
+		creating.GetUnstructured(),
+		unknown.GetUnstructured(),
+	)
+
+	expected := `
+# HELP osb_instances The number of service instances by service, plan and state.
+# TYPE osb_instances gauge
+osb_instances{plan="small-standard",service="redis-k8s",state="Available"} 1
+osb_instances{plan="small-standard",service="redis-k8s",state="Creating"} 1
+osb_instances{plan="small-standard",service="redis-k8s",state="Unknown"} 1
+`
+	err := testutil.CollectAndCompare(NewInstanceCollector(cp, lager.NewLogger("test")), strings.NewReader(expected))
+	assert.NoError(t, err)
+}
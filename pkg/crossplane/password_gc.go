@@ -0,0 +1,78 @@
+package crossplane
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composite"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+)
+
+// passwordSecretMinAge protects the password Secrets of bindings which are being created from being collected.
+const passwordSecretMinAge = 10 * time.Minute
+
+// CollectPasswordSecrets deletes the password Secrets of MariaDB bindings which have no CompositeMariaDBUserInstance,
+// e.g. because the broker stopped while binding. Secrets younger than passwordSecretMinAge are kept, as their
+// composite may still be created. Secrets owned by a User are deleted by Kubernetes together with it.
+// It returns the names of the deleted Secrets.
+func (cp Crossplane) CollectPasswordSecrets(rctx *reqcontext.ReqContext, now time.Time) ([]string, error) {
+	rctx, span := rctx.StartSpan("Crossplane.CollectPasswordSecrets")
+	defer span.End()
+
+	secrets := &corev1.SecretList{}
+	if err := cp.client.List(rctx.Context, secrets, client.InNamespace(cp.config().Namespace), client.MatchingLabels{
+		OwnerGroupLabel: mariaDBGroupVersionKind.Group,
+		OwnerKindLabel:  mariaDBUserGroupVersionKind.Kind,
+	}); err != nil {
+		return nil, err
+	}
+
+	deleted := []string{}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		bindingID := strings.TrimSuffix(secret.Name, "-password")
+		if len(secret.OwnerReferences) > 0 || bindingID == secret.Name || now.Sub(secret.CreationTimestamp.Time) < passwordSecretMinAge {
+			continue
+		}
+
+		cmp := composite.New(composite.WithGroupVersionKind(mariaDBUserGroupVersionKind))
+		err := cp.client.Get(rctx.Context, types.NamespacedName{Name: bindingID}, cmp)
+		if err == nil {
+			continue
+		}
+		if !k8serrors.IsNotFound(err) {
+			return deleted, err
+		}
+
+		if err := cp.client.Delete(rctx.Context, secret); err != nil && !k8serrors.IsNotFound(err) {
+			return deleted, err
+		}
+		rctx.Logger.Info("password-secret-collected", lager.Data{"secret": secret.Name, "binding-id": bindingID})
+		deleted = append(deleted, secret.Name)
+	}
+	return deleted, nil
+}
+
+// RunPasswordSecretCollector calls CollectPasswordSecrets every interval. It returns when ctx is done.
+func (cp Crossplane) RunPasswordSecretCollector(ctx context.Context, interval time.Duration, logger lager.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := cp.CollectPasswordSecrets(reqcontext.NewReqContext(ctx, logger, nil), time.Now()); err != nil {
+			logger.Error("collect-password-secrets", err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package crossplane
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+)
+
+func Test_CollectPasswordSecrets(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	user := composite.New(composite.WithGroupVersionKind(mariaDBUserGroupVersionKind))
+	user.SetName("bound")
+
+	owned := givenPasswordSecret("owned", now.Add(-time.Hour))
+	owned.OwnerReferences = []metav1.OwnerReference{{APIVersion: "mysql.sql.crossplane.io/v1alpha1", Kind: "User", Name: "owned", UID: "1"}}
+	cp := givenCrossplane(t, &config.Config{Namespace: "osb"},
+		user.GetUnstructured(),
+		givenPasswordSecret("bound", now.Add(-time.Hour)),
+		givenPasswordSecret("orphaned", now.Add(-time.Hour)),
+		givenPasswordSecret("in-flight", now.Add(-time.Minute)),
+		owned,
+	)
+
+	deleted, err := cp.CollectPasswordSecrets(givenRequestContext("alice"), now)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orphaned-password"}, deleted)
+
+	secrets := &corev1.SecretList{}
+	require.NoError(t, cp.client.List(context.Background(), secrets, client.InNamespace("osb")))
+	names := []string{}
+	for _, s := range secrets.Items {
+		names = append(names, s.Name)
+	}
+	assert.ElementsMatch(t, []string{"bound-password", "in-flight-password", "owned-password"}, names)
+}
+
+func givenPasswordSecret(bindingID string, created time.Time) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              bindingID + "-password",
+			Namespace:         "osb",
+			CreationTimestamp: metav1.NewTime(created),
+			Labels: map[string]string{
+				InstanceIDLabel: "db1",
+				OwnerGroupLabel: mariaDBGroupVersionKind.Group,
+				OwnerKindLabel:  mariaDBUserGroupVersionKind.Kind,
+			},
+		},
+	}
+}
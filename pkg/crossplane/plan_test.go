@@ -1,10 +1,14 @@
 package crossplane
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
 )
 
 func Test_PlanComparer(t *testing.T) {
@@ -151,3 +155,207 @@ func Test_PlanComparer(t *testing.T) {
 		})
 	}
 }
+
+func Test_PlanUpdateRules(t *testing.T) {
+	rules := PlanUpdateRules{
+		Rules: []PlanUpdateRule{
+			{Name: "upgrades", Size: SizeUpgrade},
+			{Name: "sla", SLA: "*>*"},
+			{Name: "redis-premium-upgrade", Services: []string{"redis-*"}, Size: "small>medium", SLA: "standard>premium"},
+			{Name: "mariadb-downgrades", Services: []string{"mariadb"}, Size: SizeDowngrade, SizeLadder: []string{"s", "m", "l"}},
+		},
+	}
+	pc, err := rules.Checker()
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		a, b        Labels
+		wantAllowed bool
+		wantRule    string
+		wantReasons []string
+	}{
+		"no change": {
+			a:           Labels{ServiceID: "redis", ServiceName: RedisService, PlanSize: SizeSmall, SLA: SLAStandard},
+			b:           Labels{ServiceID: "redis", ServiceName: RedisService, PlanSize: SizeSmall, SLA: SLAStandard},
+			wantAllowed: true,
+		},
+		"upgrade along the ladder": {
+			a:           Labels{ServiceID: "redis", ServiceName: RedisService, PlanSize: SizeXSmall, SLA: SLAStandard},
+			b:           Labels{ServiceID: "redis", ServiceName: RedisService, PlanSize: SizeLarge, SLA: SLAStandard},
+			wantAllowed: true,
+			wantRule:    "upgrades",
+		},
+		"SLA wildcard": {
+			a:           Labels{ServiceID: "redis", ServiceName: RedisService, PlanSize: SizeLarge, SLA: SLAPremium},
+			b:           Labels{ServiceID: "redis", ServiceName: RedisService, PlanSize: SizeLarge, SLA: SLAStandard},
+			wantAllowed: true,
+			wantRule:    "sla",
+		},
+		"combined transition of a service": {
+			a:           Labels{ServiceID: "redis", ServiceName: RedisService, PlanSize: SizeSmall, SLA: SLAStandard},
+			b:           Labels{ServiceID: "redis", ServiceName: RedisService, PlanSize: SizeMedium, SLA: SLAPremium},
+			wantAllowed: true,
+			wantRule:    "redis-premium-upgrade",
+		},
+		"per service ladder": {
+			a:           Labels{ServiceID: "mariadb", ServiceName: MariaDBService, PlanSize: "l", SLA: SLAStandard},
+			b:           Labels{ServiceID: "mariadb", ServiceName: MariaDBService, PlanSize: "s", SLA: SLAStandard},
+			wantAllowed: true,
+			wantRule:    "mariadb-downgrades",
+		},
+		"downgrade is denied": {
+			a: Labels{ServiceID: "redis", ServiceName: RedisService, PlanSize: SizeMedium, SLA: SLAStandard},
+			b: Labels{ServiceID: "redis", ServiceName: RedisService, PlanSize: SizeSmall, SLA: SLAStandard},
+			wantReasons: []string{
+				`upgrades: does not allow changing the size from "medium" to "small", only upgrades`,
+				`sla: does not allow changing the size`,
+				`redis-premium-upgrade: does not allow changing the size from "medium" to "small", only small>medium`,
+				`mariadb-downgrades: only applies to the services mariadb`,
+			},
+		},
+		"combined transition of another service is denied": {
+			a: Labels{ServiceID: "mariadb", ServiceName: MariaDBService, PlanSize: SizeSmall, SLA: SLAStandard},
+			b: Labels{ServiceID: "mariadb", ServiceName: MariaDBService, PlanSize: SizeMedium, SLA: SLAPremium},
+			wantReasons: []string{
+				`upgrades: does not allow changing the SLA`,
+				`sla: does not allow changing the size`,
+				`redis-premium-upgrade: only applies to the services redis-*`,
+				`mariadb-downgrades: size "small" is not part of the size ladder s, m, l`,
+			},
+		},
+		"service change is denied": {
+			a:           Labels{ServiceID: "redis", PlanSize: SizeSmall, SLA: SLAStandard},
+			b:           Labels{ServiceID: "mariadb", PlanSize: SizeSmall, SLA: SLAStandard},
+			wantReasons: []string{"changing the service is not allowed"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			d := pc.Explain(Plan{Labels: &tc.a}, Plan{Labels: &tc.b})
+			assert.Equal(t, tc.wantAllowed, d.Allowed)
+			assert.Equal(t, tc.wantRule, d.Rule)
+			assert.Equal(t, tc.wantReasons, d.Reasons)
+		})
+	}
+}
+
+func Test_ReadPlanUpdateRules(t *testing.T) {
+	tests := map[string]struct {
+		content string
+		wantErr string
+	}{
+		"valid": {
+			content: "size_ladder: [s, m, l]\nrules:\n  - size: upgrade\n  - sla: standard>premium\n",
+		},
+		"json": {
+			content: `{"rules": [{"name": "any", "size": "*>*", "sla": "*>*"}]}`,
+		},
+		"unknown field": {
+			content: "rules:\n  - size: upgrade\n    plan: small\n",
+			wantErr: `unknown field "plan"`,
+		},
+		"rule without transition": {
+			content: "rules:\n  - services: [redis]\n",
+			wantErr: `rule "rule 1": either size or sla is required`,
+		},
+		"invalid transition": {
+			content: "rules:\n  - name: broken\n    sla: standard\n",
+			wantErr: `rule "broken": sla: unable to parse rule: standard`,
+		},
+		"duplicate size in ladder": {
+			content: "size_ladder: [s, m, s]\nrules:\n  - size: downgrade\n",
+			wantErr: `size "s" is listed more than once in the size ladder`,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "rules.yaml")
+			require.NoError(t, os.WriteFile(file, []byte(tc.content), 0o600))
+
+			_, err := ReadPlanUpdateRules(file)
+			_, inlineErr := NewPlanUpdateChecker(&config.Config{PlanUpdateInlineRules: tc.content})
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+				assert.NoError(t, inlineErr, "inline rules must be parsed like a rules file")
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.wantErr)
+			require.Error(t, inlineErr)
+			assert.Contains(t, inlineErr.Error(), tc.wantErr)
+			assert.Contains(t, inlineErr.Error(), config.EnvPlanUpdateInlineRules)
+		})
+	}
+}
+
+func Test_PlanUpdateTargets(t *testing.T) {
+	pc, err := ParsePlanUpdateRules("small>medium", "standard>premium")
+	require.NoError(t, err)
+
+	xrd := ServiceXRD{PlanUpdateTargets: map[string][]string{
+		"small-*":   {"medium-*"},
+		"*-premium": {"large-premium"},
+	}}
+	givenPlan := func(name, sla string, targets []string) Plan {
+		return Plan{
+			Labels:        &Labels{ServiceID: "redis", PlanName: name, PlanSize: getPlanSize(name, sla), SLA: sla},
+			UpdateTargets: targets,
+		}
+	}
+
+	tests := map[string]struct {
+		a, b        Plan
+		xrd         bool
+		wantAllowed bool
+		wantReasons []string
+	}{
+		"without targets, the rules apply": {
+			a:           givenPlan("small-standard", SLAStandard, nil),
+			b:           givenPlan("medium-standard", SLAStandard, nil),
+			wantAllowed: true,
+		},
+		"composition targets replace the rules": {
+			a:           givenPlan("small-standard", SLAStandard, []string{"large-*"}),
+			b:           givenPlan("medium-standard", SLAStandard, nil),
+			wantReasons: []string{`service.syn.tools/plan-update-targets: plan "small-standard" can only be updated to large-*`},
+		},
+		"composition targets allow transitions the rules deny": {
+			a:           givenPlan("small-standard", SLAStandard, []string{"large-*"}),
+			b:           givenPlan("large-premium", SLAPremium, nil),
+			wantAllowed: true,
+		},
+		"empty composition targets deny all updates": {
+			a:           givenPlan("small-standard", SLAStandard, []string{}),
+			b:           givenPlan("small-premium", SLAPremium, nil),
+			wantReasons: []string{`service.syn.tools/plan-update-targets: plan "small-standard" can't be updated`},
+		},
+		"XRD targets of matching plans": {
+			a:           givenPlan("small-premium", SLAPremium, nil),
+			b:           givenPlan("large-premium", SLAPremium, nil),
+			xrd:         true,
+			wantAllowed: true,
+		},
+		"XRD targets deny other transitions": {
+			a:           givenPlan("medium-standard", SLAStandard, nil),
+			b:           givenPlan("medium-premium", SLAPremium, nil),
+			xrd:         true,
+			wantReasons: []string{`service.syn.tools/plan-update-targets: plan "medium-standard" can't be updated`},
+		},
+		"composition targets take precedence over the XRD": {
+			a:           givenPlan("small-standard", SLAStandard, []string{"small-premium"}),
+			b:           givenPlan("medium-standard", SLAStandard, nil),
+			xrd:         true,
+			wantReasons: []string{`service.syn.tools/plan-update-targets: plan "small-standard" can only be updated to small-premium`},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tc.xrd {
+				tc.a.UpdateTargets = xrd.UpdateTargets(&tc.a)
+			}
+			d := pc.Explain(tc.a, tc.b)
+			assert.Equal(t, tc.wantAllowed, d.Allowed)
+			assert.Equal(t, tc.wantReasons, d.Reasons)
+		})
+	}
+}
@@ -0,0 +1,430 @@
+package crossplane
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+)
+
+const (
+	// SizeUpgrade allows any change to a larger size of the size ladder.
+	SizeUpgrade = "upgrade"
+	// SizeDowngrade allows any change to a smaller size of the size ladder.
+	SizeDowngrade = "downgrade"
+)
+
+// DefaultSizeLadder orders the plan sizes from the smallest to the largest.
+var DefaultSizeLadder = []string{SizeXSmall, SizeSmall, SizeMedium, SizeLarge, SizeXLarge}
+
+// PlanUpdateRules is the structure of a plan update rules file.
+//
+// Example:
+//
+//	size_ladder: [xsmall, small, medium, large, xlarge]
+//	rules:
+//	  - name: upgrades
+//	    size: upgrade
+//	  - name: sla
+//	    sla: "*>*"
+//	  - name: redis-premium-upgrade
+//	    services: [redis-k8s]
+//	    size: small>medium
+//	    sla: standard>premium
+type PlanUpdateRules struct {
+	// SizeLadder orders the plan sizes from the smallest to the largest. Defaults to DefaultSizeLadder.
+	SizeLadder []string `json:"size_ladder,omitempty"`
+	// Rules white-list plan updates. An update is allowed if any of the rules matches.
+	Rules []PlanUpdateRule `json:"rules"`
+}
+
+// PlanUpdateRule white-lists a change of the plan size, the SLA, or both at once.
+type PlanUpdateRule struct {
+	// Name is used to explain decisions. Defaults to the position of the rule.
+	Name string `json:"name,omitempty"`
+	// Services restricts the rule to services whose ID or name matches one of the patterns. Defaults to all services.
+	Services []string `json:"services,omitempty"`
+	// Size is either `$OLD>$NEW`, which may contain wildcards, `upgrade` or `downgrade`.
+	// If empty, the rule only matches if the size does not change.
+	Size string `json:"size,omitempty"`
+	// SLA is `$OLD>$NEW`, which may contain wildcards.
+	// If empty, the rule only matches if the SLA does not change.
+	SLA string `json:"sla,omitempty"`
+	// SizeLadder overrides the size ladder of the rules file for this rule.
+	SizeLadder []string `json:"size_ladder,omitempty"`
+}
+
+// NewPlanUpdateChecker returns a PlanUpdateChecker implementing the configured plan update rules.
+// The rules file and the inline rules take precedence over the size and SLA rules.
+func NewPlanUpdateChecker(cfg *config.Config) (PlanUpdateChecker, error) {
+	if cfg.PlanUpdateRules != "" {
+		return ReadPlanUpdateRules(cfg.PlanUpdateRules)
+	}
+	if cfg.PlanUpdateInlineRules != "" {
+		return parseStructuredPlanUpdateRules([]byte(cfg.PlanUpdateInlineRules), config.EnvPlanUpdateInlineRules)
+	}
+	return ParsePlanUpdateRules(cfg.PlanUpdateSizeRule, cfg.PlanUpdateSLARule)
+}
+
+// ReadPlanUpdateRules reads a YAML or JSON plan update rules file and returns a PlanUpdateChecker that implements them.
+func ReadPlanUpdateRules(file string) (PlanUpdateChecker, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return PlanUpdateChecker{}, fmt.Errorf("unable to read plan update rules: %w", err)
+	}
+	return parseStructuredPlanUpdateRules(data, file)
+}
+
+// parseStructuredPlanUpdateRules parses YAML or JSON plan update rules read from source.
+func parseStructuredPlanUpdateRules(data []byte, source string) (PlanUpdateChecker, error) {
+	rules := PlanUpdateRules{}
+	if err := yaml.UnmarshalStrict(data, &rules); err != nil {
+		return PlanUpdateChecker{}, fmt.Errorf("unable to parse plan update rules %s: %w", source, err)
+	}
+	pc, err := rules.Checker()
+	if err != nil {
+		return PlanUpdateChecker{}, fmt.Errorf("invalid plan update rules %s: %w", source, err)
+	}
+	return pc, nil
+}
+
+// Checker validates the rules and returns a PlanUpdateChecker that implements them.
+func (r PlanUpdateRules) Checker() (PlanUpdateChecker, error) {
+	pc := PlanUpdateChecker{}
+	ladder := r.SizeLadder
+	if len(ladder) == 0 {
+		ladder = DefaultSizeLadder
+	}
+	for i, rule := range r.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i+1)
+		}
+		c, err := rule.compile(name, ladder)
+		if err != nil {
+			return pc, fmt.Errorf("rule %q: %w", name, err)
+		}
+		pc.rules = append(pc.rules, c)
+	}
+	return pc, nil
+}
+
+func (r PlanUpdateRule) compile(name string, ladder []string) (planUpdateRule, error) {
+	c := planUpdateRule{name: name, services: r.Services}
+	if r.Size == "" && r.SLA == "" {
+		return c, fmt.Errorf("either size or sla is required")
+	}
+	for _, s := range r.Services {
+		if _, err := path.Match(s, ""); err != nil {
+			return c, fmt.Errorf("service pattern %q: %w", s, err)
+		}
+	}
+	if len(r.SizeLadder) > 0 {
+		ladder = r.SizeLadder
+	}
+
+	var err error
+	switch r.Size {
+	case "":
+	case SizeUpgrade, SizeDowngrade:
+		c.size, err = newLadderTransition(r.Size, ladder)
+	default:
+		c.size, err = parsePlanTransition(r.Size)
+	}
+	if err != nil {
+		return c, fmt.Errorf("size: %w", err)
+	}
+	if r.SLA != "" {
+		c.sla, err = parsePlanTransition(r.SLA)
+		if err != nil {
+			return c, fmt.Errorf("sla: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// ParsePlanUpdateRules parses the rules given as strings and return a PlanUpgradeChecker that implements them.
+// It takes two rule strings.
+// One that defines how plan SLAs can be changed and one that defines how plan sizes are allowed to be changed.
+// Both are a `|` separated list of white-listed changes in the form of `$OLD_PLAN>$NEW_PLAN`.
+//
+// The example sizeRules `small>medium|medium>large`, will allow updating plans from small to medium and from medium to large and reject all other updates.
+// The slaRules `standard>premium|premium>standard`, will allow switching between standard and premium SLA.
+func ParsePlanUpdateRules(sizeRules, slaRules string) (PlanUpdateChecker, error) {
+	pc := PlanUpdateChecker{}
+
+	sizes, err := parsePlanUpdateRules(sizeRules)
+	if err != nil {
+		return pc, err
+	}
+	for _, t := range sizes {
+		pc.rules = append(pc.rules, planUpdateRule{name: "size " + t.String(), size: t})
+	}
+	slas, err := parsePlanUpdateRules(slaRules)
+	if err != nil {
+		return pc, err
+	}
+	for _, t := range slas {
+		pc.rules = append(pc.rules, planUpdateRule{name: "sla " + t.String(), sla: t})
+	}
+
+	return pc, nil
+}
+
+func parsePlanUpdateRules(ruleString string) ([]*planTransition, error) {
+	rules := []*planTransition{}
+	if ruleString == "" {
+		return rules, nil
+	}
+	rs := strings.Split(ruleString, "|")
+	for _, r := range rs {
+		t, err := parsePlanTransition(r)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, t)
+	}
+	return rules, nil
+}
+
+// UpdateTargets returns the plan names an instance of the plan may be updated to.
+// Update targets declared on the Composition take precedence over the ones declared on the XRD.
+// It returns nil if neither declares update targets.
+func (s ServiceXRD) UpdateTargets(p *Plan) []string {
+	if p.UpdateTargets != nil || s.PlanUpdateTargets == nil {
+		return p.UpdateTargets
+	}
+	patterns := make([]string, 0, len(s.PlanUpdateTargets))
+	for pattern := range s.PlanUpdateTargets {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+
+	targets := []string{}
+	for _, pattern := range patterns {
+		if globMatch(pattern, p.Labels.PlanName) {
+			targets = append(targets, s.PlanUpdateTargets[pattern]...)
+		}
+	}
+	return targets
+}
+
+// parseUpdateTargets parses the PlanUpdateTargetsAnnotation of a Composition.
+func parseUpdateTargets(annotations map[string]string) ([]string, error) {
+	v, ok := annotations[PlanUpdateTargetsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	targets := []string{}
+	if err := json.Unmarshal([]byte(v), &targets); err != nil {
+		return nil, fmt.Errorf("annotation %q is not a valid JSON list of plan names: %w", PlanUpdateTargetsAnnotation, err)
+	}
+	return targets, validatePatterns(targets)
+}
+
+// parseServiceUpdateTargets parses the PlanUpdateTargetsAnnotation of an XRD.
+func parseServiceUpdateTargets(annotations map[string]string) (map[string][]string, error) {
+	v, ok := annotations[PlanUpdateTargetsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	targets := map[string][]string{}
+	if err := json.Unmarshal([]byte(v), &targets); err != nil {
+		return nil, fmt.Errorf("annotation %q is not a valid JSON object of plan names: %w", PlanUpdateTargetsAnnotation, err)
+	}
+	for plan, t := range targets {
+		if err := validatePatterns(append([]string{plan}, t...)); err != nil {
+			return nil, err
+		}
+	}
+	return targets, nil
+}
+
+func validatePatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid plan name pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// PlanUpdateChecker checks whether an update is valid
+type PlanUpdateChecker struct {
+	rules []planUpdateRule
+}
+
+// PlanUpdateDecision is the result of checking a plan update against the rules.
+type PlanUpdateDecision struct {
+	Allowed bool
+	// Rule is the name of the rule which allowed the update, or PlanUpdateTargetsAnnotation if the update targets of the plan did.
+	// It's empty if the update doesn't change the size nor the SLA.
+	Rule string
+	// Reasons explain why the update is denied, one per rule.
+	Reasons []string
+}
+
+// AllowUpdate checks whether a plan upgrade from a to b is valid
+func (pc PlanUpdateChecker) AllowUpdate(a, b Plan) bool {
+	return pc.Explain(a, b).Allowed
+}
+
+// Explain checks whether a plan upgrade from a to b is valid and explains the decision.
+func (pc PlanUpdateChecker) Explain(a, b Plan) PlanUpdateDecision {
+	if a.Labels.ServiceID != b.Labels.ServiceID {
+		return PlanUpdateDecision{Reasons: []string{"changing the service is not allowed"}}
+	}
+	if a.UpdateTargets != nil && a.Labels.PlanName != b.Labels.PlanName {
+		// Update targets declared by the service replace the configured rules.
+		for _, t := range a.UpdateTargets {
+			if globMatch(t, b.Labels.PlanName) {
+				return PlanUpdateDecision{Allowed: true, Rule: PlanUpdateTargetsAnnotation}
+			}
+		}
+		if len(a.UpdateTargets) == 0 {
+			return PlanUpdateDecision{Reasons: []string{fmt.Sprintf("%s: plan %q can't be updated", PlanUpdateTargetsAnnotation, a.Labels.PlanName)}}
+		}
+		return PlanUpdateDecision{Reasons: []string{fmt.Sprintf("%s: plan %q can only be updated to %s", PlanUpdateTargetsAnnotation, a.Labels.PlanName, strings.Join(a.UpdateTargets, ", "))}}
+	}
+	if !planSizeChanged(a, b) && !planSLAChanged(a, b) {
+		return PlanUpdateDecision{Allowed: true}
+	}
+	if len(pc.rules) == 0 {
+		return PlanUpdateDecision{Reasons: []string{"no plan update rules are configured"}}
+	}
+
+	d := PlanUpdateDecision{}
+	for _, r := range pc.rules {
+		reason := r.deny(a, b)
+		if reason == "" {
+			return PlanUpdateDecision{Allowed: true, Rule: r.name}
+		}
+		d.Reasons = append(d.Reasons, fmt.Sprintf("%s: %s", r.name, reason))
+	}
+	return d
+}
+
+type planUpdateRule struct {
+	name     string
+	services []string
+	// size and sla are nil if the rule does not allow changing them.
+	size *planTransition
+	sla  *planTransition
+}
+
+// deny returns why the rule does not allow updating a to b, or an empty string if it does.
+func (r planUpdateRule) deny(a, b Plan) string {
+	if !r.appliesTo(a.Labels) {
+		return fmt.Sprintf("only applies to the services %s", strings.Join(r.services, ", "))
+	}
+	if reason := r.size.deny("size", a.Labels.PlanSize, b.Labels.PlanSize); reason != "" {
+		return reason
+	}
+	return r.sla.deny("SLA", a.Labels.SLA, b.Labels.SLA)
+}
+
+func (r planUpdateRule) appliesTo(l *Labels) bool {
+	if len(r.services) == 0 {
+		return true
+	}
+	for _, s := range r.services {
+		if globMatch(s, l.ServiceID) || globMatch(s, string(l.ServiceName)) {
+			return true
+		}
+	}
+	return false
+}
+
+// planTransition matches a change of a plan size or SLA.
+type planTransition struct {
+	from, to string
+	// ladder is set for upgrade and downgrade transitions.
+	ladder []string
+}
+
+func parsePlanTransition(s string) (*planTransition, error) {
+	a := strings.Split(s, ">")
+	if len(a) != 2 {
+		return nil, fmt.Errorf("unable to parse rule: %s", s)
+	}
+	for _, p := range a {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("unable to parse rule: %s: %w", s, err)
+		}
+	}
+	return &planTransition{from: a[0], to: a[1]}, nil
+}
+
+func newLadderTransition(direction string, ladder []string) (*planTransition, error) {
+	seen := map[string]bool{}
+	for _, s := range ladder {
+		if seen[s] {
+			return nil, fmt.Errorf("size %q is listed more than once in the size ladder", s)
+		}
+		seen[s] = true
+	}
+	return &planTransition{from: direction, ladder: ladder}, nil
+}
+
+// deny returns why the transition does not allow changing a to b, or an empty string if it does.
+// A nil transition only allows keeping the value.
+func (t *planTransition) deny(kind, a, b string) string {
+	switch {
+	case a == b:
+		return ""
+	case t == nil:
+		return fmt.Sprintf("does not allow changing the %s", kind)
+	case t.ladder != nil:
+		i, j := indexOf(t.ladder, a), indexOf(t.ladder, b)
+		for _, s := range []struct {
+			name  string
+			index int
+		}{{a, i}, {b, j}} {
+			if s.index < 0 {
+				return fmt.Sprintf("%s %q is not part of the size ladder %s", kind, s.name, strings.Join(t.ladder, ", "))
+			}
+		}
+		if (t.from == SizeUpgrade) != (j > i) {
+			return fmt.Sprintf("does not allow changing the %s from %q to %q, only %ss", kind, a, b, t.from)
+		}
+		return ""
+	case globMatch(t.from, a) && globMatch(t.to, b):
+		return ""
+	}
+	return fmt.Sprintf("does not allow changing the %s from %q to %q, only %s", kind, a, b, t)
+}
+
+func (t *planTransition) String() string {
+	if t.ladder != nil {
+		return t.from
+	}
+	return t.from + ">" + t.to
+}
+
+func globMatch(pattern, s string) bool {
+	ok, err := path.Match(pattern, s)
+	return err == nil && ok
+}
+
+func indexOf(l []string, s string) int {
+	for i, e := range l {
+		if e == s {
+			return i
+		}
+	}
+	return -1
+}
+
+func planSizeChanged(a, b Plan) bool {
+	return a.Labels != nil && b.Labels != nil && a.Labels.PlanSize != b.Labels.PlanSize
+}
+
+func planSLAChanged(a, b Plan) bool {
+	return a.Labels != nil && b.Labels != nil && a.Labels.SLA != b.Labels.SLA
+}
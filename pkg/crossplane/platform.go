@@ -0,0 +1,139 @@
+package crossplane
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composite"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vshn/crossplane-service-broker/pkg/platform"
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+)
+
+// PlatformContext returns the platform context the instance has been provisioned or last updated with.
+func (i Instance) PlatformContext() (platform.Context, error) {
+	pc := platform.Context{}
+	v, ok := i.Composite.GetAnnotations()[PlatformContextAnnotation]
+	if !ok {
+		return pc, nil
+	}
+	err := json.Unmarshal([]byte(v), &pc)
+	return pc, err
+}
+
+// PlatformSelector returns the labels selecting instances with the given platform context. Empty fields match any value.
+func PlatformSelector(pc platform.Context) client.MatchingLabels {
+	selector := client.MatchingLabels{}
+	for k, v := range platformLabels(pc) {
+		if v != "" {
+			selector[k] = v
+		}
+	}
+	return selector
+}
+
+func platformLabels(pc platform.Context) map[string]string {
+	return map[string]string{
+		PlatformLabel:         pc.Platform,
+		OrganizationGUIDLabel: pc.OrganizationGUID,
+		SpaceGUIDLabel:        pc.SpaceGUID,
+		NamespaceLabel:        pc.Namespace,
+	}
+}
+
+// setPlatformContext stores the platform context on the composite.
+// The complete context is stored as annotation, the fields used for filtering as labels.
+// Nothing is changed if the context is empty.
+func setPlatformContext(cmp *composite.Unstructured, pc platform.Context) error {
+	if pc.IsEmpty() {
+		return nil
+	}
+	data, err := json.Marshal(pc)
+	if err != nil {
+		return err
+	}
+
+	annotations := cmp.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[PlatformContextAnnotation] = string(data)
+	cmp.SetAnnotations(annotations)
+
+	l := cmp.GetLabels()
+	if l == nil {
+		l = map[string]string{}
+	}
+	for k, v := range platformLabels(pc) {
+		delete(l, k)
+		// Values which aren't valid label values are only kept in the annotation.
+		if v != "" && len(validation.IsValidLabelValue(v)) == 0 {
+			l[k] = v
+		}
+	}
+	cmp.SetLabels(l)
+	return nil
+}
+
+// setOriginatingIdentity stores the platform user who created obj as annotation.
+// Nothing is changed if the identity is empty.
+func setOriginatingIdentity(obj metav1.Object, id platform.Identity) error {
+	if id.IsEmpty() {
+		return nil
+	}
+	data, err := json.Marshal(id)
+	if err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[OriginatingIdentityAnnotation] = string(data)
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// Instances lists the instances of all configured services which match the labels, ordered by ID.
+func (cp Crossplane) Instances(rctx *reqcontext.ReqContext, matching client.MatchingLabels) ([]*Instance, error) {
+	rctx, span := rctx.StartSpan("Crossplane.Instances")
+	defer span.End()
+
+	plans, err := cp.Plans(rctx, cp.config().ServiceIDs)
+	if err != nil {
+		return nil, err
+	}
+	gvks := map[schema.GroupVersionKind]bool{}
+	for _, p := range plans {
+		gvk, err := p.GVK()
+		if err != nil {
+			return nil, err
+		}
+		gvks[gvk] = true
+	}
+
+	instances := []*Instance{}
+	for gvk := range gvks {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := cp.client.List(rctx.Context, list, client.HasLabels{InstanceIDLabel}, matching); err != nil {
+			return nil, err
+		}
+		for _, item := range list.Items {
+			instance, err := newInstance(&composite.Unstructured{Unstructured: item})
+			if err != nil {
+				return nil, err
+			}
+			instances = append(instances, instance)
+		}
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].ID() < instances[j].ID()
+	})
+	return instances, nil
+}
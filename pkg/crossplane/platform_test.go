@@ -0,0 +1,109 @@
+package crossplane
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+	"github.com/vshn/crossplane-service-broker/pkg/platform"
+)
+
+func Test_setPlatformContext(t *testing.T) {
+	cmp := givenComposite("1", "alice", "small-standard")
+	pc := platform.Context{
+		Platform:         platform.CloudFoundry,
+		OrganizationGUID: "org-1",
+		OrganizationName: "acme",
+		SpaceGUID:        "space with spaces",
+	}
+	require.NoError(t, setPlatformContext(cmp, pc))
+
+	l := cmp.GetLabels()
+	assert.Equal(t, "cloudfoundry", l[PlatformLabel])
+	assert.Equal(t, "org-1", l[OrganizationGUIDLabel])
+	assert.NotContains(t, l, SpaceGUIDLabel, "invalid label values must only be stored in the annotation")
+	assert.Equal(t, "redis", l[ServiceIDLabel], "other labels must be kept")
+
+	instance, err := newInstance(cmp)
+	require.NoError(t, err)
+	got, err := instance.PlatformContext()
+	require.NoError(t, err)
+	assert.Equal(t, pc, got)
+
+	require.NoError(t, setPlatformContext(cmp, platform.Context{}))
+	assert.Equal(t, "org-1", cmp.GetLabels()[OrganizationGUIDLabel], "an empty context must not change the composite")
+}
+
+func Test_Instances(t *testing.T) {
+	comp := givenLintComposition("redis-small", "redis", RedisService, "small-standard", SLAStandard)
+	comp.Spec.CompositeTypeRef.APIVersion = testRedisGVK.GroupVersion().String()
+	comp.Spec.CompositeTypeRef.Kind = testRedisGVK.Kind
+
+	cf := givenComposite("1", "alice", "small-standard")
+	require.NoError(t, setPlatformContext(cf, platform.Context{Platform: platform.CloudFoundry, OrganizationGUID: "org-1"}))
+	k8s := givenComposite("2", "alice", "small-standard")
+	require.NoError(t, setPlatformContext(k8s, platform.Context{Platform: platform.Kubernetes, Namespace: "team-a"}))
+	none := givenComposite("3", "bob", "small-standard")
+
+	cp := givenCrossplane(t, &config.Config{ServiceIDs: []string{"redis"}},
+		&comp, cf.GetUnstructured(), k8s.GetUnstructured(), none.GetUnstructured())
+
+	tests := map[string]struct {
+		selector client.MatchingLabels
+		want     []string
+	}{
+		"all instances": {
+			selector: PlatformSelector(platform.Context{}),
+			want:     []string{"1", "2", "3"},
+		},
+		"by platform": {
+			selector: PlatformSelector(platform.Context{Platform: platform.Kubernetes}),
+			want:     []string{"2"},
+		},
+		"by organization": {
+			selector: PlatformSelector(platform.Context{OrganizationGUID: "org-1"}),
+			want:     []string{"1"},
+		},
+		"no match": {
+			selector: PlatformSelector(platform.Context{Namespace: "team-b"}),
+			want:     []string{},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			instances, err := cp.Instances(givenRequestContext("alice"), tc.selector)
+			require.NoError(t, err)
+			got := []string{}
+			for _, i := range instances {
+				got = append(got, i.ID())
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func Test_CreateInstance_PlatformMetadata(t *testing.T) {
+	plan := givenPlan("small-standard")
+	plan.Composition.Name = "redis-small"
+	plan.Composition.Labels = map[string]string{
+		ServiceNameLabel: string(RedisService),
+		ServiceIDLabel:   "redis",
+		PlanNameLabel:    "small-standard",
+	}
+	cp := givenCrossplane(t, &config.Config{UsernameClaim: "sub"})
+
+	rctx := givenRequestContext("cloud-controller")
+	rctx.Platform = platform.Context{Platform: platform.Kubernetes, Namespace: "team-a"}
+	rctx.OriginatingIdentity = platform.Identity{Platform: platform.Kubernetes, Username: "alice"}
+	require.NoError(t, cp.CreateInstance(rctx, "1", plan, map[string]interface{}{}))
+
+	instance, ok, err := cp.Instance(rctx, "1", plan)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, "team-a", instance.Composite.GetLabels()[NamespaceLabel])
+	assert.Equal(t, "cloud-controller", instance.Composite.GetLabels()[PrincipalLabel])
+	assert.JSONEq(t, `{"platform":"kubernetes","username":"alice"}`, instance.Composite.GetAnnotations()[OriginatingIdentityAnnotation])
+}
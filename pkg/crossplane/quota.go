@@ -0,0 +1,108 @@
+package crossplane
+
+import (
+	"fmt"
+	"net/http"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/pivotal-cf/brokerapi/v8/domain/apiresponses"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vshn/crossplane-service-broker/pkg/api/auth"
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+	"github.com/vshn/crossplane-service-broker/pkg/tracing"
+)
+
+var quotaExceededCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "osb_quota_exceeded_total",
+	Help: "The total number of provisioning requests which were rejected because the principal exceeded a quota.",
+}, []string{"principal", "service", "plan", "quota"})
+
+// CheckInstanceQuota verifies that the principal of the current request is allowed to provision another
+// instance of the given plan. Instances are counted using the PrincipalLabel.
+// A FailureResponse with status code 422 is returned if a quota is exceeded.
+func (cp Crossplane) CheckInstanceQuota(rctx *reqcontext.ReqContext, plan *Plan) error {
+	rctx, span := rctx.StartSpan("Crossplane.CheckInstanceQuota", tracing.PlanIDAttribute.String(plan.Composition.Name))
+	defer span.End()
+
+	if cp.config().QuotaPerService <= 0 && cp.config().QuotaPerPlan <= 0 {
+		return nil
+	}
+
+	principal, err := auth.PrincipalFromContext(rctx.Context, cp.config())
+	if err != nil {
+		return err
+	}
+
+	quotas := []struct {
+		name   string
+		limit  int
+		labels client.MatchingLabels
+	}{
+		{
+			name:  "service",
+			limit: cp.config().QuotaPerService,
+			labels: client.MatchingLabels{
+				PrincipalLabel: string(principal),
+				ServiceIDLabel: plan.Labels.ServiceID,
+			},
+		},
+		{
+			name:  "plan",
+			limit: cp.config().QuotaPerPlan,
+			labels: client.MatchingLabels{
+				PrincipalLabel: string(principal),
+				ServiceIDLabel: plan.Labels.ServiceID,
+				PlanNameLabel:  plan.Labels.PlanName,
+			},
+		},
+	}
+
+	for _, q := range quotas {
+		if q.limit <= 0 {
+			continue
+		}
+		count, err := cp.countInstances(rctx, plan, q.labels)
+		if err != nil {
+			return err
+		}
+		if count < q.limit {
+			continue
+		}
+
+		rctx.Logger.Info("quota-exceeded", lager.Data{"quota": q.name, "limit": q.limit, "instances": count})
+		quotaExceededCounter.WithLabelValues(string(principal), string(plan.Labels.ServiceName), plan.Labels.PlanName, q.name).Inc()
+		return apiresponses.NewFailureResponseBuilder(
+			fmt.Errorf("quota exceeded: %q already owns %d of at most %d instances per %s", principal, count, q.limit, q.name),
+			http.StatusUnprocessableEntity,
+			"quota-exceeded",
+		).WithErrorKey("QuotaExceeded").Build()
+	}
+	return nil
+}
+
+// countInstances counts all instances of the plan's composite type which match the given labels.
+// Instances which are being deleted are not counted.
+func (cp Crossplane) countInstances(rctx *reqcontext.ReqContext, plan *Plan, labels client.MatchingLabels) (int, error) {
+	gvk, err := plan.GVK()
+	if err != nil {
+		return 0, err
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+	if err := cp.client.List(rctx.Context, list, labels); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, item := range list.Items {
+		if item.GetDeletionTimestamp() == nil {
+			count++
+		}
+	}
+	return count, nil
+}
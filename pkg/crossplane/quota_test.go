@@ -0,0 +1,125 @@
+package crossplane
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composite"
+	xv1 "github.com/crossplane/crossplane/apis/apiextensions/v1"
+	"github.com/pascaldekloe/jwt"
+	"github.com/pivotal-cf/brokerapi/v8/domain/apiresponses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/vshn/crossplane-service-broker/pkg/api/auth"
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+	"github.com/vshn/crossplane-service-broker/pkg/reqcontext"
+)
+
+var testRedisGVK = schema.GroupVersionKind{Group: "syn.tools", Version: "v1alpha1", Kind: "CompositeRedisInstance"}
+
+func Test_CheckInstanceQuota(t *testing.T) {
+	plan := givenPlan("small-standard")
+	objs := []client.Object{
+		givenComposite("1", "alice", "small-standard").GetUnstructured(),
+		givenComposite("2", "alice", "medium-standard").GetUnstructured(),
+		givenComposite("3", "bob", "small-standard").GetUnstructured(),
+	}
+
+	tests := map[string]struct {
+		perService int
+		perPlan    int
+		principal  string
+		wantErr    bool
+	}{
+		"no quota": {
+			principal: "alice",
+		},
+		"service quota not reached": {
+			perService: 3,
+			principal:  "alice",
+		},
+		"service quota reached": {
+			perService: 2,
+			principal:  "alice",
+			wantErr:    true,
+		},
+		"plan quota reached": {
+			perPlan:   1,
+			principal: "alice",
+			wantErr:   true,
+		},
+		"plan quota of other principal": {
+			perPlan:   2,
+			principal: "bob",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cp := givenCrossplane(t, &config.Config{
+				UsernameClaim:   "sub",
+				QuotaPerService: tc.perService,
+				QuotaPerPlan:    tc.perPlan,
+			}, objs...)
+
+			err := cp.CheckInstanceQuota(givenRequestContext(tc.principal), plan)
+			if !tc.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			var apiErr *apiresponses.FailureResponse
+			require.ErrorAs(t, err, &apiErr)
+			assert.Equal(t, http.StatusUnprocessableEntity, apiErr.ValidatedStatusCode(nil))
+			assert.Equal(t, "QuotaExceeded", apiErr.ErrorResponse().(apiresponses.ErrorResponse).Error)
+		})
+	}
+}
+
+func givenCrossplane(t *testing.T, cfg *config.Config, objs ...client.Object) *Crossplane {
+	scheme := runtime.NewScheme()
+	require.NoError(t, Register(scheme))
+	return newCrossplane(cfg, fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build())
+}
+
+func givenRequestContext(principal string) *reqcontext.ReqContext {
+	ctx := context.WithValue(context.Background(), auth.AuthenticationMethodPropertyName, auth.AuthenticationMethodBearerToken)
+	ctx = context.WithValue(ctx, auth.TokenPropertyName, &jwt.Claims{Set: map[string]interface{}{"sub": principal}})
+	return reqcontext.NewReqContext(ctx, lager.NewLogger("test"), nil)
+}
+
+func givenPlan(name string) *Plan {
+	return &Plan{
+		Composition: &xv1.Composition{
+			Spec: xv1.CompositionSpec{
+				CompositeTypeRef: xv1.TypeReference{
+					APIVersion: testRedisGVK.GroupVersion().String(),
+					Kind:       testRedisGVK.Kind,
+				},
+			},
+		},
+		Labels: &Labels{
+			ServiceName: RedisService,
+			ServiceID:   "redis",
+			PlanName:    name,
+		},
+	}
+}
+
+func givenComposite(id, principal, plan string) *composite.Unstructured {
+	cmp := composite.New(composite.WithGroupVersionKind(testRedisGVK))
+	cmp.SetName(id)
+	cmp.SetLabels(map[string]string{
+		ServiceNameLabel: string(RedisService),
+		ServiceIDLabel:   "redis",
+		PlanNameLabel:    plan,
+		InstanceIDLabel:  id,
+		PrincipalLabel:   principal,
+	})
+	return cmp
+}
@@ -47,7 +47,7 @@ func NewMariadbServiceBinder(c *Crossplane, instance *Instance, logger lager.Log
 }
 
 // Bind on a MariaDB instance is not supported - only a database referencing an instance can be bound.
-func (msb MariadbServiceBinder) Bind(_ context.Context, _ string) (Credentials, error) {
+func (msb MariadbServiceBinder) Bind(_ context.Context, _ string, _ map[string]interface{}) (Credentials, error) {
 	return nil, apiresponses.NewFailureResponseBuilder(
 		fmt.Errorf("service MariaDB Galera Cluster is not bindable. "+
 			"You can create a bindable database on this cluster using "+
@@ -89,3 +89,8 @@ func (msb MariadbServiceBinder) Deprovisionable(ctx context.Context) error {
 func (msb MariadbServiceBinder) GetBinding(_ context.Context, _ string) (Credentials, error) {
 	return nil, errNotImplemented
 }
+
+// CheckDownsize refuses plans whose storage capacity is smaller than the storage used by the instance.
+func (msb MariadbServiceBinder) CheckDownsize(ctx context.Context, plan *Plan) error {
+	return msb.cp.checkStorageFits(ctx, msb.instance, plan, msb.logger)
+}
@@ -21,6 +21,9 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/vshn/crossplane-service-broker/pkg/logging"
+	"github.com/vshn/crossplane-service-broker/pkg/platform"
 )
 
 const (
@@ -31,6 +34,16 @@ const (
 	instanceParamsParentReferenceName = "parent_reference"
 	// instanceSpecParamsParentReferencePath is the path to an instance's parent reference parameter
 	instanceSpecParamsParentReferencePath = instanceSpecParamsPath + "." + instanceParamsParentReferenceName
+
+	// bindParamsPrivilegesName is the name of the bind parameter selecting the privileges of a user
+	bindParamsPrivilegesName = "privileges"
+)
+
+// Privileges a MariaDB user can be bound with. Without the privileges parameter, the default of the composition applies.
+const (
+	PrivilegesReadOnly  = "read-only"
+	PrivilegesReadWrite = "read-write"
+	PrivilegesAdmin     = "admin"
 )
 
 var (
@@ -46,6 +59,11 @@ var (
 	}
 )
 
+var (
+	_ BindValidater  = &MariadbDatabaseServiceBinder{}
+	_ BindRollbacker = &MariadbDatabaseServiceBinder{}
+)
+
 // MariadbDatabaseServiceBinder defines a specific Mariadb service with enough data to retrieve connection credentials.
 type MariadbDatabaseServiceBinder struct {
 	serviceBinder
@@ -63,7 +81,7 @@ func NewMariadbDatabaseServiceBinder(c *Crossplane, instance *Instance, logger l
 }
 
 // Bind creates a MariaDB binding composite.
-func (msb MariadbDatabaseServiceBinder) Bind(ctx context.Context, bindingID string) (Credentials, error) {
+func (msb MariadbDatabaseServiceBinder) Bind(ctx context.Context, bindingID string, params map[string]interface{}) (Credentials, error) {
 	parentRef, err := msb.instance.ParentReference()
 	if err != nil {
 		return nil, err
@@ -79,6 +97,7 @@ func (msb MariadbDatabaseServiceBinder) Bind(ctx context.Context, bindingID stri
 		bindingID,
 		msb.instance.ID(),
 		parentRef,
+		params,
 	)
 	if err != nil {
 		return nil, err
@@ -103,28 +122,41 @@ func (msb MariadbDatabaseServiceBinder) Bind(ctx context.Context, bindingID stri
 		return nil, fmt.Errorf("Could not get parent instance: %w", err)
 	}
 	cn := parent.GetLabels()["service.syn.tools/cluster"]
-	creds := createCredentials(endpoint, bindingID, pw, msb.instance.ID(), msb.instance.Labels.ParentID, cn, msb.cp.config.EnableMetrics, msb.cp.config.MetricsDomain)
+	creds := createCredentials(endpoint, bindingID, pw, msb.instance.ID(), msb.instance.Labels.ParentID, cn, msb.cp.config().EnableMetrics, msb.cp.config().MetricsDomain)
 
 	return creds, nil
 }
 
 // Unbind deletes the created User and Grant.
 func (msb MariadbDatabaseServiceBinder) Unbind(ctx context.Context, bindingID string) error {
+	cmp := composite.New(composite.WithGroupVersionKind(mariaDBUserGroupVersionKind))
+	if err := msb.cp.client.Get(ctx, types.NamespacedName{Name: bindingID}, cmp); err != nil {
+		return fmt.Errorf("could not mark credentials for deletion: could not get binding: %w", err)
+	}
 
-	if err := msb.markCredentialsForDeletion(ctx, bindingID); err != nil {
+	if err := msb.markCredentialsForDeletion(ctx, cmp); err != nil {
 		return fmt.Errorf("could not mark credentials for deletion: %w", err)
 	}
 
-	cmp := composite.New(composite.WithGroupVersionKind(mariaDBUserGroupVersionKind))
-	cmp.SetName(bindingID)
 	return msb.cp.client.Delete(ctx, cmp, client.PropagationPolicy(metav1.DeletePropagationForeground))
 }
 
-func (msb MariadbDatabaseServiceBinder) markCredentialsForDeletion(ctx context.Context, bindingID string) error {
+// RollbackBind deletes the user composite and the password Secret of a binding directly.
+// Unbind can't be used, as the resources of a just created composite don't exist yet.
+func (msb MariadbDatabaseServiceBinder) RollbackBind(ctx context.Context, bindingID string) error {
 	cmp := composite.New(composite.WithGroupVersionKind(mariaDBUserGroupVersionKind))
-	if err := msb.cp.client.Get(ctx, types.NamespacedName{Name: bindingID}, cmp); err != nil {
-		return fmt.Errorf("could not get binding: %w", err)
+	cmp.SetName(bindingID)
+	if err := msb.cp.client.Delete(ctx, cmp, client.PropagationPolicy(metav1.DeletePropagationForeground)); err != nil && !k8serrors.IsNotFound(err) {
+		return err
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf(secretName, bindingID), Namespace: msb.cp.config().Namespace}}
+	if err := msb.cp.client.Delete(ctx, secret); err != nil && !k8serrors.IsNotFound(err) {
+		return err
 	}
+	return nil
+}
+
+func (msb MariadbDatabaseServiceBinder) markCredentialsForDeletion(ctx context.Context, cmp *composite.Unstructured) error {
 
 	userRef := corev1.ObjectReference{}
 	for _, r := range cmp.GetResourceReferences() {
@@ -139,10 +171,10 @@ func (msb MariadbDatabaseServiceBinder) markCredentialsForDeletion(ctx context.C
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      fmt.Sprintf(secretName, cmp.GetName()),
-			Namespace: msb.cp.config.Namespace,
+			Namespace: msb.cp.config().Namespace,
 		},
 	}
-	if err := msb.cp.client.Get(ctx, types.NamespacedName{Name: fmt.Sprintf(secretName, cmp.GetName()), Namespace: msb.cp.config.Namespace}, secret); err != nil {
+	if err := msb.cp.client.Get(ctx, types.NamespacedName{Name: fmt.Sprintf(secretName, cmp.GetName()), Namespace: msb.cp.config().Namespace}, secret); err != nil {
 		return fmt.Errorf("failed to fetch secret: %w", err)
 	}
 
@@ -202,7 +234,7 @@ func (msb MariadbDatabaseServiceBinder) GetBinding(ctx context.Context, bindingI
 	}
 	cn := parent.GetLabels()["service.syn.tools/cluster"]
 	pw := string(secret.Data[xrv1.ResourceCredentialsSecretPasswordKey])
-	creds := createCredentials(endpoint, bindingID, pw, msb.instance.ID(), msb.instance.Labels.ParentID, cn, msb.cp.config.EnableMetrics, msb.cp.config.MetricsDomain)
+	creds := createCredentials(endpoint, bindingID, pw, msb.instance.ID(), msb.instance.Labels.ParentID, cn, msb.cp.config().EnableMetrics, msb.cp.config().MetricsDomain)
 
 	return creds, nil
 }
@@ -225,12 +257,32 @@ func (msb MariadbDatabaseServiceBinder) ValidateProvisionParams(ctx context.Cont
 	return paramsMap, nil
 }
 
-func (msb MariadbDatabaseServiceBinder) createBinding(ctx context.Context, bindingID, instanceID, parentReference string) (string, error) {
-	pw, err := password.Generate()
-	if err != nil {
-		return "", err
+// ValidateBindParams ensures that only known privileges are requested.
+func (msb MariadbDatabaseServiceBinder) ValidateBindParams(_ context.Context, params json.RawMessage) (map[string]interface{}, error) {
+	paramsMap := map[string]interface{}{}
+	if len(params) == 0 {
+		return paramsMap, nil
+	}
+	if err := json.Unmarshal(params, &paramsMap); err != nil {
+		return nil, err
 	}
+	for k, v := range paramsMap {
+		if k != bindParamsPrivilegesName {
+			return nil, fmt.Errorf("unknown parameter %q", k)
+		}
+		switch v {
+		case PrivilegesReadOnly, PrivilegesReadWrite, PrivilegesAdmin:
+		default:
+			return nil, fmt.Errorf("parameter %q must be one of %q, %q or %q", k, PrivilegesReadOnly, PrivilegesReadWrite, PrivilegesAdmin)
+		}
+	}
+	return paramsMap, nil
+}
 
+// createBinding creates the password Secret and the user composite of a binding and returns the password.
+// Binding again reuses an existing password Secret, but fails if the existing composite has other privileges.
+// If the composite can't be created, the Secret is deleted again.
+func (msb MariadbDatabaseServiceBinder) createBinding(ctx context.Context, bindingID, instanceID, parentReference string, params map[string]interface{}) (string, error) {
 	labels := map[string]string{
 		InstanceIDLabel:      instanceID,
 		ParentIDLabel:        parentReference,
@@ -239,17 +291,7 @@ func (msb MariadbDatabaseServiceBinder) createBinding(ctx context.Context, bindi
 		OwnerKindLabel:       mariaDBUserGroupVersionKind.Kind,
 	}
 
-	secret := &corev1.Secret{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      fmt.Sprintf(secretName, bindingID),
-			Namespace: msb.cp.config.Namespace,
-			Labels:    labels,
-		},
-		Data: map[string][]byte{
-			xrv1.ResourceCredentialsSecretPasswordKey: []byte(pw),
-		},
-	}
-	err = msb.cp.client.Create(ctx, secret)
+	secret, err := msb.ensurePasswordSecret(ctx, bindingID, labels)
 	if err != nil {
 		return "", err
 	}
@@ -260,18 +302,94 @@ func (msb MariadbDatabaseServiceBinder) createBinding(ctx context.Context, bindi
 	cmp.SetCompositionReference(&corev1.ObjectReference{
 		Name: planName,
 	})
-	if err := fieldpath.Pave(cmp.Object).SetValue(instanceSpecParamsParentReferencePath, parentReference); err != nil {
-		return "", err
-	}
-
-	msb.logger.Debug("create-binding", lager.Data{"instance": cmp})
-	err = msb.cp.client.Create(ctx, cmp)
+	err = msb.createUser(ctx, cmp, params, parentReference)
 	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		if delErr := msb.cp.client.Delete(ctx, secret); delErr != nil && !k8serrors.IsNotFound(delErr) {
+			msb.logger.Error("delete-password-secret", delErr, lager.Data{"binding-id": bindingID})
+		}
 		return "", err
 	}
+	if err != nil {
+		if err := msb.ensureSamePrivileges(ctx, bindingID, params); err != nil {
+			return "", err
+		}
+	}
 	return string(secret.Data[xrv1.ResourceCredentialsSecretPasswordKey]), nil
 }
 
+// ensureSamePrivileges returns ErrBindingAlreadyExists if the existing user composite of the binding
+// has been created with other privileges than the requested ones.
+func (msb MariadbDatabaseServiceBinder) ensureSamePrivileges(ctx context.Context, bindingID string, params map[string]interface{}) error {
+	existing := composite.New(composite.WithGroupVersionKind(mariaDBUserGroupVersionKind))
+	if err := msb.cp.client.Get(ctx, types.NamespacedName{Name: bindingID}, existing); err != nil {
+		return err
+	}
+	got, _ := fieldpath.Pave(existing.Object).GetString(instanceSpecParamsPath + "." + bindParamsPrivilegesName)
+	want, _ := params[bindParamsPrivilegesName].(string)
+	if got != want {
+		return apiresponses.ErrBindingAlreadyExists
+	}
+	return nil
+}
+
+// ensurePasswordSecret creates the password Secret of a binding with a new password.
+// An existing Secret of the same instance is returned as it is, which makes retrying a failed bind possible.
+func (msb MariadbDatabaseServiceBinder) ensurePasswordSecret(ctx context.Context, bindingID string, labels map[string]string) (*corev1.Secret, error) {
+	name := fmt.Sprintf(secretName, bindingID)
+	namespace := msb.cp.config().Namespace
+
+	existing := &corev1.Secret{}
+	err := msb.cp.client.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, existing)
+	if err == nil {
+		if existing.Labels[InstanceIDLabel] != labels[InstanceIDLabel] {
+			return nil, fmt.Errorf("password secret %q belongs to another instance", name)
+		}
+		return existing, nil
+	}
+	if !k8serrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to fetch secret: %w", err)
+	}
+
+	pw, err := password.Generate()
+	if err != nil {
+		return nil, err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    labels,
+		},
+		Data: map[string][]byte{
+			xrv1.ResourceCredentialsSecretPasswordKey: []byte(pw),
+		},
+	}
+	if err := msb.cp.client.Create(ctx, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// createUser sets the parameters of the user composite and creates it.
+func (msb MariadbDatabaseServiceBinder) createUser(ctx context.Context, cmp *composite.Unstructured, params map[string]interface{}, parentReference string) error {
+	for k, v := range params {
+		if err := fieldpath.Pave(cmp.Object).SetValue(instanceSpecParamsPath+"."+k, v); err != nil {
+			return err
+		}
+	}
+	if err := fieldpath.Pave(cmp.Object).SetValue(instanceSpecParamsParentReferencePath, parentReference); err != nil {
+		return err
+	}
+	if id, err := platform.IdentityFromContext(ctx); err == nil {
+		if err := setOriginatingIdentity(cmp, id); err != nil {
+			return err
+		}
+	}
+
+	msb.logger.Debug("create-binding", lager.Data{"instance": logging.Redact(cmp)})
+	return msb.cp.client.Create(ctx, cmp)
+}
+
 func mapMariadbEndpoint(data map[string][]byte) (*Endpoint, error) {
 	hostBytes, ok := data[xrv1.ResourceCredentialsSecretEndpointKey]
 	if !ok {
@@ -0,0 +1,160 @@
+package crossplane
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"code.cloudfoundry.org/lager"
+	"github.com/crossplane/crossplane-runtime/pkg/fieldpath"
+	"github.com/crossplane/crossplane-runtime/pkg/resource/unstructured/composite"
+	"github.com/pivotal-cf/brokerapi/v8/domain/apiresponses"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+)
+
+func Test_ValidateBindParams(t *testing.T) {
+	tests := map[string]struct {
+		params  string
+		want    map[string]interface{}
+		wantErr string
+	}{
+		"no parameters": {
+			want: map[string]interface{}{},
+		},
+		"read-only": {
+			params: `{"privileges": "read-only"}`,
+			want:   map[string]interface{}{"privileges": "read-only"},
+		},
+		"admin": {
+			params: `{"privileges": "admin"}`,
+			want:   map[string]interface{}{"privileges": "admin"},
+		},
+		"unknown privileges": {
+			params:  `{"privileges": "superuser"}`,
+			wantErr: `parameter "privileges" must be one of "read-only", "read-write" or "admin"`,
+		},
+		"unknown parameter": {
+			params:  `{"database": "other"}`,
+			wantErr: `unknown parameter "database"`,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			msb := NewMariadbDatabaseServiceBinder(nil, nil, lager.NewLogger("test"))
+			var raw json.RawMessage
+			if tc.params != "" {
+				raw = json.RawMessage(tc.params)
+			}
+			got, err := msb.ValidateBindParams(context.Background(), raw)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func Test_createBinding_Privileges(t *testing.T) {
+	cp := givenCrossplane(t, &config.Config{Namespace: "osb"})
+	msb := NewMariadbDatabaseServiceBinder(cp, nil, lager.NewLogger("test"))
+
+	_, err := msb.createBinding(context.Background(), "b1", "db1", "cluster1", map[string]interface{}{"privileges": PrivilegesReadOnly})
+	require.NoError(t, err)
+
+	cmp := composite.New(composite.WithGroupVersionKind(mariaDBUserGroupVersionKind))
+	require.NoError(t, cp.client.Get(context.Background(), types.NamespacedName{Name: "b1"}, cmp))
+	params, err := fieldpath.Pave(cmp.Object).GetValue(instanceSpecParamsPath)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"privileges": "read-only", "parent_reference": "cluster1"}, params)
+}
+
+func Test_createBinding_OtherPrivileges(t *testing.T) {
+	ctx := context.Background()
+	cp := givenCrossplane(t, &config.Config{Namespace: "osb"})
+	msb := NewMariadbDatabaseServiceBinder(cp, nil, lager.NewLogger("test"))
+
+	_, err := msb.createBinding(ctx, "b1", "db1", "cluster1", map[string]interface{}{"privileges": PrivilegesReadOnly})
+	require.NoError(t, err)
+
+	_, err = msb.createBinding(ctx, "b1", "db1", "cluster1", map[string]interface{}{"privileges": PrivilegesReadOnly})
+	assert.NoError(t, err, "binding again with the same privileges must succeed")
+	_, err = msb.createBinding(ctx, "b1", "db1", "cluster1", map[string]interface{}{"privileges": PrivilegesAdmin})
+	assert.Equal(t, apiresponses.ErrBindingAlreadyExists, err)
+	_, err = msb.createBinding(ctx, "b1", "db1", "cluster1", nil)
+	assert.Equal(t, apiresponses.ErrBindingAlreadyExists, err, "the default privileges differ from read-only")
+
+	err = cp.client.Get(ctx, types.NamespacedName{Name: "b1-password", Namespace: "osb"}, &corev1.Secret{})
+	assert.NoError(t, err, "the password secret of the existing binding must be kept")
+}
+
+func Test_createBinding_Retry(t *testing.T) {
+	ctx := context.Background()
+	cp := givenCrossplane(t, &config.Config{Namespace: "osb"})
+	msb := NewMariadbDatabaseServiceBinder(cp, nil, lager.NewLogger("test"))
+
+	pw, err := msb.createBinding(ctx, "b1", "db1", "cluster1", nil)
+	require.NoError(t, err)
+	again, err := msb.createBinding(ctx, "b1", "db1", "cluster1", nil)
+	require.NoError(t, err, "binding again must reuse the password secret")
+	assert.Equal(t, pw, again)
+
+	events := &corev1.EventList{}
+	require.NoError(t, cp.client.List(ctx, events))
+	assert.Empty(t, events.Items, "the Bound event is recorded by the broker on the instance")
+
+	_, err = msb.createBinding(ctx, "b1", "db2", "cluster1", nil)
+	assert.EqualError(t, err, `password secret "b1-password" belongs to another instance`)
+}
+
+func Test_createBinding_CleanupOnFailure(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	require.NoError(t, Register(scheme))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			if _, ok := obj.(*composite.Unstructured); ok {
+				return errors.New("admission webhook denied the request")
+			}
+			return c.Create(ctx, obj, opts...)
+		},
+	}).Build()
+	cp := newCrossplane(&config.Config{Namespace: "osb"}, c)
+	msb := NewMariadbDatabaseServiceBinder(cp, nil, lager.NewLogger("test"))
+
+	_, err := msb.createBinding(ctx, "b1", "db1", "cluster1", nil)
+	assert.EqualError(t, err, "admission webhook denied the request")
+
+	err = c.Get(ctx, types.NamespacedName{Name: "b1-password", Namespace: "osb"}, &corev1.Secret{})
+	assert.True(t, k8serrors.IsNotFound(err), "the password secret must be deleted, got %v", err)
+}
+
+func Test_RollbackBind(t *testing.T) {
+	ctx := context.Background()
+	cp := givenCrossplane(t, &config.Config{Namespace: "osb"})
+	msb := NewMariadbDatabaseServiceBinder(cp, nil, lager.NewLogger("test"))
+
+	_, err := msb.createBinding(ctx, "b1", "db1", "cluster1", nil)
+	require.NoError(t, err)
+	assert.Error(t, msb.Unbind(ctx, "b1"), "unbinding needs the user of the composite, which doesn't exist yet")
+
+	require.NoError(t, RollbackBind(ctx, msb, "b1"))
+	err = cp.client.Get(ctx, types.NamespacedName{Name: "b1"}, composite.New(composite.WithGroupVersionKind(mariaDBUserGroupVersionKind)))
+	assert.True(t, k8serrors.IsNotFound(err), "the user composite must be deleted, got %v", err)
+	err = cp.client.Get(ctx, types.NamespacedName{Name: "b1-password", Namespace: "osb"}, &corev1.Secret{})
+	assert.True(t, k8serrors.IsNotFound(err), "the password secret must be deleted, got %v", err)
+
+	assert.NoError(t, RollbackBind(ctx, msb, "b1"), "rolling back a removed binding must succeed")
+}
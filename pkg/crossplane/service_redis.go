@@ -34,7 +34,7 @@ func NewRedisServiceBinder(c *Crossplane, instance *Instance, logger lager.Logge
 }
 
 // Bind retrieves the necessary external IP, password and ports.
-func (rsb RedisServiceBinder) Bind(ctx context.Context, bindingID string) (Credentials, error) {
+func (rsb RedisServiceBinder) Bind(ctx context.Context, bindingID string, _ map[string]interface{}) (Credentials, error) {
 	return rsb.GetBinding(ctx, bindingID)
 }
 
@@ -96,12 +96,12 @@ func (rsb RedisServiceBinder) GetBinding(ctx context.Context, bindingID string)
 		},
 		"ca.crt": string(caCert),
 	}
-	if rsb.cp.config.EnableMetrics {
+	if rsb.cp.config().EnableMetrics {
 		creds["metricsEndpoints"] = []string{
-			fmt.Sprintf("http://%s.%s.%s", rsb.instance.ID(), cn, rsb.cp.config.MetricsDomain),
-			fmt.Sprintf("http://%s.%s.%s/redis/0", rsb.instance.ID(), cn, rsb.cp.config.MetricsDomain),
-			fmt.Sprintf("http://%s.%s.%s/redis/1", rsb.instance.ID(), cn, rsb.cp.config.MetricsDomain),
-			fmt.Sprintf("http://%s.%s.%s/redis/2", rsb.instance.ID(), cn, rsb.cp.config.MetricsDomain),
+			fmt.Sprintf("http://%s.%s.%s", rsb.instance.ID(), cn, rsb.cp.config().MetricsDomain),
+			fmt.Sprintf("http://%s.%s.%s/redis/0", rsb.instance.ID(), cn, rsb.cp.config().MetricsDomain),
+			fmt.Sprintf("http://%s.%s.%s/redis/1", rsb.instance.ID(), cn, rsb.cp.config().MetricsDomain),
+			fmt.Sprintf("http://%s.%s.%s/redis/2", rsb.instance.ID(), cn, rsb.cp.config().MetricsDomain),
 		}
 	}
 
@@ -143,3 +143,8 @@ func interfaceIsSlice(t interface{}) bool {
 		return false
 	}
 }
+
+// CheckDownsize refuses plans whose storage capacity is smaller than the storage used by the instance.
+func (rsb RedisServiceBinder) CheckDownsize(ctx context.Context, plan *Plan) error {
+	return rsb.cp.checkStorageFits(ctx, rsb.instance, plan, rsb.logger)
+}
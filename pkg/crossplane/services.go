@@ -51,7 +51,8 @@ var (
 // ServiceBinder is an interface for service specific implementation for binding,
 // retrieving credentials, etc.
 type ServiceBinder interface {
-	Bind(ctx context.Context, bindingID string) (Credentials, error)
+	// Bind creates the binding. params are the parameters returned by BindValidater, if the service implements it.
+	Bind(ctx context.Context, bindingID string, params map[string]interface{}) (Credentials, error)
 	Unbind(ctx context.Context, bindingID string) error
 	Deprovisionable(ctx context.Context) error
 	GetBinding(ctx context.Context, bindingID string) (Credentials, error)
@@ -64,6 +65,29 @@ type ProvisionValidater interface {
 	ValidateProvisionParams(ctx context.Context, params json.RawMessage) (map[string]interface{}, error)
 }
 
+// BindValidater enables service implementations to check the parameters of bind requests.
+// Bind parameters of services which don't implement it are ignored.
+type BindValidater interface {
+	// ValidateBindParams checks the params for validity. If valid, it returns the parameters passed to Bind.
+	ValidateBindParams(ctx context.Context, params json.RawMessage) (map[string]interface{}, error)
+}
+
+// BindRollbacker enables service implementations to remove a binding which has just been created,
+// if the broker can't return its credentials. Services which don't implement it are unbound instead.
+type BindRollbacker interface {
+	// RollbackBind deletes everything Bind created for the binding.
+	RollbackBind(ctx context.Context, bindingID string) error
+}
+
+// RollbackBind removes a binding which has just been created by sb.
+// It uses the rollback of the service if it implements BindRollbacker, and unbinds otherwise.
+func RollbackBind(ctx context.Context, sb ServiceBinder, bindingID string) error {
+	if br, ok := sb.(BindRollbacker); ok {
+		return br.RollbackBind(ctx, bindingID)
+	}
+	return sb.Unbind(ctx, bindingID)
+}
+
 // ServiceBinderFactory reads the composite's labels service name and instantiates an appropriate ServiceBinder.
 // FIXME(mw): determine fate of this. We might not need differentiation anymore, once provider-helm is upgraded.
 func ServiceBinderFactory(c *Crossplane, serviceName ServiceName, instance *Instance, logger lager.Logger) (ServiceBinder, error) {
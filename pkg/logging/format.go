@@ -0,0 +1,116 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// levelFatal is the slog level lager.FATAL messages are logged with.
+const levelFatal = slog.LevelError + 4
+
+// logfmtSink writes messages as logfmt key-value pairs, e.g.
+//
+//	ts=2021-01-01T00:00:00.000000000Z level=info source=crossplane-service-broker message=crossplane-service-broker.server-start tls=false
+type logfmtSink struct {
+	w io.Writer
+}
+
+func (s *logfmtSink) Log(log lager.LogFormat) {
+	buf := &bytes.Buffer{}
+	writeLogfmtPair(buf, "ts", timestamp(log).Format(time.RFC3339Nano))
+	writeLogfmtPair(buf, "level", log.LogLevel.String())
+	writeLogfmtPair(buf, "source", log.Source)
+	writeLogfmtPair(buf, "message", log.Message)
+	for _, k := range sortedKeys(log.Data) {
+		v := dataValue(log.Data[k])
+		if s, ok := v.(string); ok {
+			writeLogfmtPair(buf, k, s)
+			continue
+		}
+		b, _ := json.Marshal(v)
+		writeLogfmtPair(buf, k, string(b))
+	}
+	buf.Truncate(buf.Len() - 1)
+	buf.WriteByte('\n')
+	_, _ = s.w.Write(buf.Bytes())
+}
+
+func writeLogfmtPair(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	buf.WriteByte('=')
+	if value == "" || strings.ContainsAny(value, " =\"\t\r\n") {
+		buf.WriteString(strconv.Quote(value))
+	} else {
+		buf.WriteString(value)
+	}
+	buf.WriteByte(' ')
+}
+
+// slogSink writes messages using the JSON handler of log/slog.
+type slogSink struct {
+	handler slog.Handler
+}
+
+func newSlogSink(w io.Writer) *slogSink {
+	return &slogSink{
+		handler: slog.NewJSONHandler(&lockedWriter{w: w}, &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == slog.LevelKey && len(groups) == 0 && a.Value.Any() == levelFatal {
+					a.Value = slog.StringValue("FATAL")
+				}
+				return a
+			},
+		}),
+	}
+}
+
+func (s *slogSink) Log(log lager.LogFormat) {
+	r := slog.NewRecord(timestamp(log), slogLevel(log.LogLevel), log.Message, 0)
+	r.AddAttrs(slog.String("source", log.Source))
+	for _, k := range sortedKeys(log.Data) {
+		r.AddAttrs(slog.Any(k, dataValue(log.Data[k])))
+	}
+	_ = s.handler.Handle(context.Background(), r)
+}
+
+func slogLevel(l lager.LogLevel) slog.Level {
+	switch l {
+	case lager.DEBUG:
+		return slog.LevelDebug
+	case lager.INFO:
+		return slog.LevelInfo
+	case lager.ERROR:
+		return slog.LevelError
+	}
+	return levelFatal
+}
+
+// timestamp parses the timestamp of a lager message, which is formatted as seconds since the epoch.
+func timestamp(log lager.LogFormat) time.Time {
+	ts, err := strconv.ParseFloat(log.Timestamp, 64)
+	if err != nil {
+		return time.Now().UTC()
+	}
+	sec, frac := math.Modf(ts)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
+
+func sortedKeys(data lager.Data) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
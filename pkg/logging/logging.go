@@ -0,0 +1,92 @@
+// Package logging provides the lager sinks of the service broker, supporting several output formats
+// and per component log levels.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"code.cloudfoundry.org/lager"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+)
+
+// ComponentKey is the name of the log data field which contains the component a message is logged by.
+const ComponentKey = "component"
+
+// NewLogger creates a new logger with a sink according to the log settings of cfg.
+func NewLogger(w io.Writer, cfg *config.Config, component string) lager.Logger {
+	logger := lager.NewLogger(component)
+	logger.RegisterSink(NewSink(w, cfg))
+	return logger
+}
+
+// NewSink returns a sink writing messages in the configured format to w.
+// Messages below the configured log level, or the level configured for their component, are dropped.
+func NewSink(w io.Writer, cfg *config.Config) lager.Sink {
+	var sink lager.Sink
+	switch cfg.LogFormat {
+	case config.LogFormatJSON:
+		sink = lager.NewWriterSink(w, lager.DEBUG)
+	case config.LogFormatLogfmt:
+		sink = &logfmtSink{w: &lockedWriter{w: w}}
+	case config.LogFormatSlog:
+		sink = newSlogSink(w)
+	default:
+		sink = lager.NewPrettySink(w, lager.DEBUG)
+	}
+	return &levelSink{
+		sink:      sink,
+		level:     cfg.LogLevel,
+		overrides: cfg.LogLevelOverrides,
+	}
+}
+
+// levelSink drops messages below the log level of their component.
+type levelSink struct {
+	sink      lager.Sink
+	level     lager.LogLevel
+	overrides map[string]lager.LogLevel
+}
+
+func (s *levelSink) Log(log lager.LogFormat) {
+	level := s.level
+	if component, ok := log.Data[ComponentKey].(string); ok {
+		if l, ok := s.overrides[component]; ok {
+			level = l
+		}
+	}
+	if log.LogLevel < level {
+		return
+	}
+	s.sink.Log(log)
+}
+
+// dataValue returns v as it is logged by lager: structs, maps and slices are rendered as JSON.
+func dataValue(v interface{}) interface{} {
+	switch v.(type) {
+	case string, bool, int, int32, int64, uint, uint32, uint64, float32, float64, nil:
+		return v
+	case error:
+		return fmt.Sprint(v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%+v", v)
+	}
+	return json.RawMessage(b)
+}
+
+// lockedWriter serializes writes of a sink.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(b []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(b)
+}
@@ -0,0 +1,34 @@
+package logging
+
+import (
+	"encoding/json"
+
+	"code.cloudfoundry.org/lager"
+)
+
+// redactedKeyPattern matches the names of parameters whose values must not be logged.
+const redactedKeyPattern = `(?i)(pass(word)?|pwd|secret|token|credentials?|private[-_]?key|api[-_]?key)$`
+
+// redacter redacts sensitive keys as well as the values lager considers sensitive, e.g. private keys.
+var redacter = mustRedacter(lager.NewJSONRedacter([]string{redactedKeyPattern}, nil))
+
+func mustRedacter(r *lager.JSONRedacter, err error) *lager.JSONRedacter {
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Redact returns a copy of v, rendered as JSON, with the values of sensitive parameters replaced by `*REDACTED*`.
+// It is meant for logging objects which contain user provided parameters.
+func Redact(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var redacted interface{}
+	if err := json.Unmarshal(redacter.Redact(b), &redacted); err != nil {
+		return nil
+	}
+	return redacted
+}
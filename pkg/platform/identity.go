@@ -0,0 +1,82 @@
+package platform
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pivotal-cf/brokerapi/v8/middlewares"
+)
+
+// Identity is the user on whose behalf the platform sent a request, decoded from the
+// `X-Broker-API-Originating-Identity` header.
+// See https://github.com/openservicebrokerapi/servicebroker/blob/master/profile.md#originating-identity-header.
+type Identity struct {
+	Platform string `json:"platform"`
+
+	// UserID is sent by Cloud Foundry.
+	UserID string `json:"user_id,omitempty"`
+
+	// Username, UID, Groups and Extra are sent by Kubernetes.
+	Username string              `json:"username,omitempty"`
+	UID      string              `json:"uid,omitempty"`
+	Groups   []string            `json:"groups,omitempty"`
+	Extra    map[string][]string `json:"extra,omitempty"`
+}
+
+// ParseIdentity decodes the value of the originating identity header, which consists of the platform
+// and the base64 encoded JSON identity separated by a space. An empty header results in an empty Identity.
+// Of platforms other than Cloud Foundry and Kubernetes, only the platform is kept.
+func ParseIdentity(header string) (Identity, error) {
+	id := Identity{}
+	if header == "" {
+		return id, nil
+	}
+	p, value, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || p == "" {
+		return id, fmt.Errorf("invalid originating identity %q: expected platform and value", header)
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return id, fmt.Errorf("invalid originating identity: %w", err)
+	}
+
+	switch p {
+	case CloudFoundry, Kubernetes:
+		if err := json.Unmarshal(raw, &id); err != nil {
+			return Identity{}, fmt.Errorf("invalid originating identity: %w", err)
+		}
+	}
+	id.Platform = p
+	return id, nil
+}
+
+// IdentityFromContext decodes the originating identity header stored in ctx by the brokerapi middleware.
+func IdentityFromContext(ctx context.Context) (Identity, error) {
+	header, _ := ctx.Value(middlewares.OriginatingIdentityKey).(string)
+	return ParseIdentity(header)
+}
+
+// IsEmpty returns true if no originating identity has been sent.
+func (i Identity) IsEmpty() bool {
+	return i.Platform == ""
+}
+
+// User returns the user ID on Cloud Foundry and the username on Kubernetes.
+func (i Identity) User() string {
+	if i.UserID != "" {
+		return i.UserID
+	}
+	return i.Username
+}
+
+// String returns the platform and user, e.g. `cloudfoundry:683ea748-3092-4ff4-b656-39cacc4d5360`.
+// It returns an empty string for an empty identity.
+func (i Identity) String() string {
+	if i.IsEmpty() {
+		return ""
+	}
+	return i.Platform + ":" + i.User()
+}
@@ -0,0 +1,63 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseIdentity(t *testing.T) {
+	tests := map[string]struct {
+		header   string
+		want     Identity
+		wantUser string
+		wantErr  bool
+	}{
+		"no header": {
+			want: Identity{},
+		},
+		"cloud foundry": {
+			// {"user_id":"683ea748"}
+			header:   "cloudfoundry eyJ1c2VyX2lkIjoiNjgzZWE3NDgifQ==",
+			want:     Identity{Platform: CloudFoundry, UserID: "683ea748"},
+			wantUser: "cloudfoundry:683ea748",
+		},
+		"kubernetes": {
+			// {"username":"alice","uid":"1","groups":["admins"]}
+			header:   "kubernetes eyJ1c2VybmFtZSI6ImFsaWNlIiwidWlkIjoiMSIsImdyb3VwcyI6WyJhZG1pbnMiXX0=",
+			want:     Identity{Platform: Kubernetes, Username: "alice", UID: "1", Groups: []string{"admins"}},
+			wantUser: "kubernetes:alice",
+		},
+		"other platform": {
+			header:   "nomad eyJ1c2VyX2lkIjoiNjgzZWE3NDgifQ==",
+			want:     Identity{Platform: "nomad"},
+			wantUser: "nomad:",
+		},
+		"missing value": {
+			header:  "cloudfoundry",
+			wantErr: true,
+		},
+		"invalid base64": {
+			header:  "cloudfoundry not-base64!",
+			wantErr: true,
+		},
+		"invalid JSON": {
+			// {"user_id":
+			header:  "cloudfoundry eyJ1c2VyX2lkIjo=",
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseIdentity(tc.header)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+			assert.Equal(t, tc.wantUser, got.String())
+		})
+	}
+}
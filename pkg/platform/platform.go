@@ -0,0 +1,79 @@
+// Package platform parses the context platforms send with OSB requests.
+// See https://github.com/openservicebrokerapi/servicebroker/blob/master/profile.md#context-object.
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// CloudFoundry is the platform value sent by Cloud Foundry.
+	CloudFoundry = "cloudfoundry"
+	// Kubernetes is the platform value sent by Kubernetes.
+	Kubernetes = "kubernetes"
+)
+
+// Context is the platform context of a request.
+// The fields which don't apply to the platform are empty.
+type Context struct {
+	Platform string `json:"platform,omitempty"`
+
+	// OrganizationGUID, OrganizationName, SpaceGUID and SpaceName are sent by Cloud Foundry.
+	OrganizationGUID string `json:"organization_guid,omitempty"`
+	OrganizationName string `json:"organization_name,omitempty"`
+	SpaceGUID        string `json:"space_guid,omitempty"`
+	SpaceName        string `json:"space_name,omitempty"`
+
+	// Namespace and ClusterID are sent by Kubernetes.
+	Namespace string `json:"namespace,omitempty"`
+	ClusterID string `json:"clusterid,omitempty"`
+
+	InstanceName string `json:"instance_name,omitempty"`
+}
+
+// Parse parses the raw context of a request. An empty raw context results in an empty Context.
+func Parse(raw json.RawMessage) (Context, error) {
+	c := Context{}
+	if len(raw) == 0 {
+		return c, nil
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return Context{}, fmt.Errorf("invalid context: %w", err)
+	}
+
+	switch c.Platform {
+	case CloudFoundry:
+		c.Namespace, c.ClusterID = "", ""
+	case Kubernetes:
+		c.OrganizationGUID, c.OrganizationName, c.SpaceGUID, c.SpaceName = "", "", "", ""
+	default:
+		c = Context{Platform: c.Platform, InstanceName: c.InstanceName}
+	}
+	return c, nil
+}
+
+// IsEmpty returns true if no platform context has been sent.
+func (c Context) IsEmpty() bool {
+	return c == Context{}
+}
+
+// Attributes returns the fields which are set, keyed by their names in the OSB API.
+func (c Context) Attributes() map[string]string {
+	attrs := map[string]string{}
+	for k, v := range map[string]string{
+		"platform":          c.Platform,
+		"organization_guid": c.OrganizationGUID,
+		"organization_name": c.OrganizationName,
+		"space_guid":        c.SpaceGUID,
+		"space_name":        c.SpaceName,
+		"namespace":         c.Namespace,
+		"clusterid":         c.ClusterID,
+		"instance_name":     c.InstanceName,
+	} {
+		if v != "" {
+			attrs[k] = v
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,66 @@
+package platform
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Parse(t *testing.T) {
+	tests := map[string]struct {
+		raw     string
+		want    Context
+		wantErr bool
+	}{
+		"no context": {
+			want: Context{},
+		},
+		"cloud foundry": {
+			raw: `{"platform":"cloudfoundry","organization_guid":"org-1","organization_name":"acme","space_guid":"space-1","space_name":"dev","instance_name":"cache","namespace":"ignored"}`,
+			want: Context{
+				Platform:         CloudFoundry,
+				OrganizationGUID: "org-1",
+				OrganizationName: "acme",
+				SpaceGUID:        "space-1",
+				SpaceName:        "dev",
+				InstanceName:     "cache",
+			},
+		},
+		"kubernetes": {
+			raw: `{"platform":"kubernetes","namespace":"team-a","clusterid":"c-1","instance_name":"cache","space_guid":"ignored"}`,
+			want: Context{
+				Platform:     Kubernetes,
+				Namespace:    "team-a",
+				ClusterID:    "c-1",
+				InstanceName: "cache",
+			},
+		},
+		"other platform": {
+			raw:  `{"platform":"nomad","namespace":"ignored","instance_name":"cache"}`,
+			want: Context{Platform: "nomad", InstanceName: "cache"},
+		},
+		"invalid JSON": {
+			raw:     `{"platform":`,
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := Parse([]byte(tc.raw))
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func Test_Attributes(t *testing.T) {
+	c := Context{Platform: Kubernetes, Namespace: "team-a"}
+	assert.Equal(t, map[string]string{"platform": "kubernetes", "namespace": "team-a"}, c.Attributes())
+	assert.False(t, c.IsEmpty())
+	assert.True(t, Context{}.IsEmpty())
+}
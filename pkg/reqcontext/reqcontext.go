@@ -2,9 +2,15 @@ package reqcontext
 
 import (
 	"context"
+	"encoding/json"
 
 	"code.cloudfoundry.org/lager"
 	"github.com/pivotal-cf/brokerapi/v8/middlewares"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/vshn/crossplane-service-broker/pkg/platform"
+	"github.com/vshn/crossplane-service-broker/pkg/tracing"
 )
 
 // ReqContext provides a simple struct to contain required request scoped context. In doing so we avoid
@@ -13,7 +19,14 @@ import (
 type ReqContext struct {
 	Context       context.Context
 	CorrelationID string
+	TraceID       string
 	Logger        lager.Logger
+	// Platform is the context the platform sent with the request, e.g. the Cloud Foundry space or Kubernetes namespace.
+	// It's empty for requests without context. Authorization decisions can be based on it.
+	Platform platform.Context
+	// OriginatingIdentity is the platform user on whose behalf the request has been sent.
+	// It's empty if the platform didn't send one or it is invalid.
+	OriginatingIdentity platform.Identity
 }
 
 // NewReqContext reads data from context and sets up the request context.
@@ -27,9 +40,48 @@ func NewReqContext(ctx context.Context, logger lager.Logger, logData lager.Data)
 	}
 	logData["correlation-id"] = id
 
-	return &ReqContext{
-		Context:       ctx,
-		CorrelationID: id,
-		Logger:        logger.WithData(logData),
+	traceID := ""
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		traceID = sc.TraceID().String()
+		logData["trace-id"] = traceID
+	}
+
+	identity, identityErr := platform.IdentityFromContext(ctx)
+	if !identity.IsEmpty() {
+		logData["originating-identity"] = identity.String()
+	}
+
+	rctx := &ReqContext{
+		Context:             ctx,
+		CorrelationID:       id,
+		TraceID:             traceID,
+		Logger:              logger.WithData(logData),
+		OriginatingIdentity: identity,
+	}
+	if identityErr != nil {
+		rctx.Logger.Debug("invalid-originating-identity", lager.Data{"error": identityErr.Error()})
+	}
+	return rctx
+}
+
+// StartSpan starts a child span of the current request and returns a copy of the request context containing it.
+// The caller must end the returned span.
+func (rctx *ReqContext) StartSpan(name string, attrs ...attribute.KeyValue) (*ReqContext, trace.Span) {
+	ctx, span := tracing.Tracer().Start(rctx.Context, name, trace.WithAttributes(attrs...))
+	c := *rctx
+	c.Context = ctx
+	return &c, span
+}
+
+// SetPlatform parses the raw platform context of the request and adds it to the log data.
+func (rctx *ReqContext) SetPlatform(raw json.RawMessage) error {
+	pc, err := platform.Parse(raw)
+	if err != nil {
+		return err
+	}
+	rctx.Platform = pc
+	if !pc.IsEmpty() {
+		rctx.Logger = rctx.Logger.WithData(lager.Data{"platform-context": pc.Attributes()})
 	}
+	return nil
 }
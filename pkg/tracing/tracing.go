@@ -0,0 +1,128 @@
+// Package tracing sets up OpenTelemetry tracing and provides the http middlewares which start a span
+// for every service broker request.
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pivotal-cf/brokerapi/v8/middlewares"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/vshn/crossplane-service-broker/pkg/config"
+)
+
+const (
+	// TracerName is the name of the tracer used for all spans of the service broker.
+	TracerName = "github.com/vshn/crossplane-service-broker"
+
+	serviceName = "crossplane-service-broker"
+
+	// CorrelationIDAttribute is the span attribute containing the correlation ID of a request.
+	CorrelationIDAttribute = attribute.Key("osb.correlation_id")
+	// InstanceIDAttribute is the span attribute containing the ID of a service instance.
+	InstanceIDAttribute = attribute.Key("osb.instance_id")
+	// PlanIDAttribute is the span attribute containing the ID of a plan.
+	PlanIDAttribute = attribute.Key("osb.plan_id")
+)
+
+// Tracer returns the tracer of the service broker.
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// Setup configures the global tracer provider and the W3C trace context propagator.
+// Spans are exported to the OTLP/HTTP endpoint configured in cfg. If no endpoint is configured,
+// only the propagator is set up and all spans are discarded.
+// The returned function flushes and stops the exporter and must be called before exiting.
+func Setup(ctx context.Context, cfg *config.Config, version string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if cfg.TracingEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.TracingEndpoint))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(serviceName),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TracingSampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Handler wraps next and starts a server span for every request.
+// A trace context passed by the platform in the `traceparent` header is continued.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		ctx, span := Tracer().Start(ctx, req.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(req.Method),
+				semconv.URLPath(req.URL.Path),
+				attribute.String("osb.api_version", req.Header.Get("X-Broker-API-Version")),
+			),
+		)
+		defer span.End()
+
+		rw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, req.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rw.status))
+		if rw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rw.status))
+		}
+	})
+}
+
+// RouteMiddleware names the current span after the matched route and links it to the correlation ID of the request.
+// It must be used after the brokerapi correlation ID middleware.
+func RouteMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		span := trace.SpanFromContext(req.Context())
+		if route := mux.CurrentRoute(req); route != nil {
+			if tpl, err := route.GetPathTemplate(); err == nil {
+				span.SetName(req.Method + " " + tpl)
+				span.SetAttributes(semconv.HTTPRoute(tpl))
+			}
+		}
+		if id, ok := req.Context().Value(middlewares.CorrelationIDKey).(string); ok {
+			span.SetAttributes(CorrelationIDAttribute.String(id))
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}